@@ -0,0 +1,88 @@
+package sys
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultWebhookTimeout limits the time of webhook requests if NewWebhookNotifier is not given a client.
+const DefaultWebhookTimeout = 10 * time.Second
+
+type (
+	// LogNotifier writes alerts to a logger.
+	LogNotifier struct {
+		logger *log.Logger
+	}
+
+	// WebhookNotifier posts alerts as JSON to a URL.
+	WebhookNotifier struct {
+		url    string
+		client *http.Client
+	}
+
+	// NATSNotifier publishes alerts as JSON on a subject.
+	NATSNotifier struct {
+		nc      *nats.Conn
+		subject string
+	}
+)
+
+// NewLogNotifier creates a notifier writing to given logger, or to the standard logger if nil.
+func NewLogNotifier(logger *log.Logger) *LogNotifier {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(alert Alert) error {
+	n.logger.Printf("[%s] %s on %s (value: %g, since: %s)",
+		alert.State, alert.Rule, serverName(alert.Server), alert.Value, alert.Since.Format(time.RFC3339))
+	return nil
+}
+
+// NewWebhookNotifier creates a notifier posting to given URL using the client or, if nil,
+// a client with DefaultWebhookTimeout. As alerts are notified synchronously, the client should have a timeout.
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	return &WebhookNotifier{url: url, client: client}
+}
+
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+// NewNATSNotifier creates a notifier publishing on given subject.
+func NewNATSNotifier(nc *nats.Conn, subject string) *NATSNotifier {
+	return &NATSNotifier{nc: nc, subject: subject}
+}
+
+func (n *NATSNotifier) Notify(alert Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	return n.nc.Publish(n.subject, payload)
+}
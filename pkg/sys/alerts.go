@@ -0,0 +1,521 @@
+package sys
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultAlertInterval = 30 * time.Second
+
+	// DefaultAlertServerTimeout is the time after which a server missing from alert data
+	// is considered removed, unless AlertData.Servers is set.
+	DefaultAlertServerTimeout = 5 * time.Minute
+
+	// healthzStatusMetric is compared with health status names, e.g. "healthz.status != ok".
+	healthzStatusMetric = "healthz.status"
+)
+
+type (
+	// AlertRule is a threshold condition evaluated for each server, e.g. "varz.mem > 2GiB for 5m".
+	//
+	// Rule expressions have the following format:
+	//
+	//	<metric> [rate] <operator> <threshold> [for <duration>]
+	//
+	// Metric is one of MetricNames or "healthz.status". Operator is one of >, >=, <, <=, == and !=.
+	// Threshold is a number with an optional size suffix (KB, MB, GB, TB, KiB, MiB, GiB, TiB)
+	// or, for "healthz.status", one of "ok", "na" and "error".
+	// With "rate", the per-second rate of change of the metric is compared instead of its value;
+	// threshold may then have a "/s", "/m" or "/h" suffix.
+	// If duration is set, the condition has to hold for given time before the alert fires.
+	AlertRule struct {
+		Name      string        `json:"name"`
+		Expr      string        `json:"expr"`
+		Metric    string        `json:"metric"`
+		Rate      bool          `json:"rate,omitempty"`
+		Operator  string        `json:"operator"`
+		Threshold float64       `json:"threshold"`
+		For       time.Duration `json:"for,omitempty"`
+	}
+
+	// Alert is the state of a rule for a single server.
+	Alert struct {
+		Rule   string     `json:"rule"`
+		Expr   string     `json:"expr"`
+		Server ServerInfo `json:"server"`
+		State  AlertState `json:"state"`
+		Value  float64    `json:"value"`
+		Since  time.Time  `json:"since"`
+		Time   time.Time  `json:"time"`
+	}
+
+	AlertState string
+
+	// AlertData contains monitoring responses against which rules are evaluated.
+	AlertData struct {
+		// Servers are the servers expected to respond. If not set, servers which
+		// responded within DefaultAlertServerTimeout are expected.
+		Servers []ServerInfo
+
+		Varz    []VarzResp
+		Statsz  []ServerStatszResp
+		Jsz     []JSZResp
+		Healthz []HealthzResp
+	}
+
+	// AlertEngine evaluates rules and notifies about alerts which started firing or got resolved.
+	AlertEngine struct {
+		mu        sync.Mutex
+		rules     []*AlertRule
+		notifiers []Notifier
+		alerts    map[alertKey]*Alert
+		samples   map[alertKey]Point
+		servers   map[string]seenServer
+	}
+
+	// Notifier is notified when an alert starts firing or gets resolved.
+	Notifier interface {
+		Notify(Alert) error
+	}
+
+	alertKey struct {
+		rule   int
+		server string
+	}
+
+	serverValue struct {
+		server ServerInfo
+		value  float64
+	}
+
+	seenServer struct {
+		server ServerInfo
+		time   time.Time
+	}
+)
+
+// Possible alert states
+const (
+	AlertPending  AlertState = "pending"  // Condition holds, but not for long enough
+	AlertFiring   AlertState = "firing"   // Condition holds
+	AlertResolved AlertState = "resolved" // Condition no longer holds for a firing alert
+)
+
+var sizeUnits = map[string]float64{
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+var healthStatuses = map[string]HealthStatus{
+	"ok":    StatusOK,
+	"na":    StatusUnavailable,
+	"error": StatusError,
+}
+
+// ParseAlertRule parses a rule expression. The expression is used as the rule name.
+func ParseAlertRule(expr string) (*AlertRule, error) {
+	tokens := strings.Fields(expr)
+	rule := &AlertRule{Name: expr, Expr: expr}
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: invalid rule %q: %s", ErrValidation, expr, fmt.Sprintf(format, args...))
+	}
+	if len(tokens) < 3 {
+		return nil, invalid("expected <metric> [rate] <operator> <threshold> [for <duration>]")
+	}
+
+	rule.Metric, tokens = tokens[0], tokens[1:]
+	if rule.Metric != healthzStatusMetric && !IsMetric(rule.Metric) {
+		return nil, invalid("unknown metric %q", rule.Metric)
+	}
+	if tokens[0] == "rate" {
+		if rule.Metric == healthzStatusMetric {
+			return nil, invalid("rate cannot be used with %s", healthzStatusMetric)
+		}
+		rule.Rate, tokens = true, tokens[1:]
+	}
+	if len(tokens) < 2 {
+		return nil, invalid("missing threshold")
+	}
+
+	rule.Operator = tokens[0]
+	switch rule.Operator {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return nil, invalid("unknown operator %q", rule.Operator)
+	}
+
+	var err error
+	if rule.Metric == healthzStatusMetric {
+		status, ok := healthStatuses[tokens[1]]
+		if !ok {
+			return nil, invalid("unknown health status %q", tokens[1])
+		}
+		if rule.Operator != "==" && rule.Operator != "!=" {
+			return nil, invalid("health status can only be compared with == and !=")
+		}
+		rule.Threshold = float64(status)
+	} else if rule.Threshold, err = parseThreshold(tokens[1], rule.Rate); err != nil {
+		return nil, invalid("%s", err)
+	}
+	tokens = tokens[2:]
+
+	if len(tokens) > 0 {
+		if len(tokens) != 2 || tokens[0] != "for" {
+			return nil, invalid("unexpected %q", strings.Join(tokens, " "))
+		}
+		if rule.For, err = time.ParseDuration(tokens[1]); err != nil || rule.For < 0 {
+			return nil, invalid("invalid duration %q", tokens[1])
+		}
+	}
+	return rule, nil
+}
+
+// parseThreshold parses a number with optional size unit and, for rates, time unit.
+func parseThreshold(s string, rate bool) (float64, error) {
+	per := 1.0
+	if idx := strings.LastIndex(s, "/"); idx >= 0 {
+		if !rate {
+			return 0, fmt.Errorf("time unit can only be used with rate: %q", s)
+		}
+		switch s[idx+1:] {
+		case "s":
+		case "m":
+			per = 60
+		case "h":
+			per = 3600
+		default:
+			return 0, fmt.Errorf("unknown time unit: %q", s[idx+1:])
+		}
+		s = s[:idx]
+	}
+	multiplier := 1.0
+	for unit, m := range sizeUnits {
+		if strings.HasSuffix(s, unit) {
+			s, multiplier = strings.TrimSuffix(s, unit), m
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid threshold: %q", s)
+	}
+	return value * multiplier / per, nil
+}
+
+func (r *AlertRule) endpoint() Endpoint {
+	switch {
+	case r.Metric == healthzStatusMetric:
+		return EndpointHealthz
+	case strings.HasPrefix(r.Metric, "varz."):
+		return EndpointVarz
+	case strings.HasPrefix(r.Metric, "statsz."):
+		return EndpointStatsz
+	default:
+		return EndpointJsz
+	}
+}
+
+func (r *AlertRule) matches(value float64) bool {
+	switch r.Operator {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	default:
+		return value != r.Threshold
+	}
+}
+
+// values returns the metric for each server included in the data.
+// For health rules, expected servers missing from HEALTHZ responses are reported as unavailable.
+func (r *AlertRule) values(data AlertData, expected map[string]ServerInfo) []serverValue {
+	values := make([]serverValue, 0)
+	switch r.endpoint() {
+	case EndpointVarz:
+		for i := range data.Varz {
+			values = append(values, serverValue{data.Varz[i].Server, varzMetrics[r.Metric](&data.Varz[i].Varz)})
+		}
+	case EndpointStatsz:
+		for i := range data.Statsz {
+			values = append(values, serverValue{data.Statsz[i].Server, statszMetrics[r.Metric](&data.Statsz[i].Statsz)})
+		}
+	case EndpointJsz:
+		for i := range data.Jsz {
+			if !data.Jsz[i].JSInfo.Disabled {
+				values = append(values, serverValue{data.Jsz[i].Server, jszMetrics[r.Metric](&data.Jsz[i].JSInfo)})
+			}
+		}
+	case EndpointHealthz:
+		// HEALTHZ was not polled
+		if data.Healthz == nil {
+			break
+		}
+		responded := make(map[string]struct{}, len(data.Healthz))
+		for i := range data.Healthz {
+			responded[data.Healthz[i].Server.ID] = struct{}{}
+			values = append(values, serverValue{data.Healthz[i].Server, float64(data.Healthz[i].Healthz.Status)})
+		}
+		ids := make([]string, 0, len(expected))
+		for id := range expected {
+			if _, ok := responded[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			values = append(values, serverValue{expected[id], float64(StatusUnavailable)})
+		}
+	}
+	return values
+}
+
+func NewAlertEngine(rules []*AlertRule, notifiers ...Notifier) *AlertEngine {
+	return &AlertEngine{
+		rules:     rules,
+		notifiers: notifiers,
+		alerts:    make(map[alertKey]*Alert),
+		samples:   make(map[alertKey]Point),
+		servers:   make(map[string]seenServer),
+	}
+}
+
+// Evaluate evaluates all rules against the data collected at given time.
+// It returns alerts which started firing or got resolved, after notifying all notifiers about them.
+// Expected servers missing from HEALTHZ responses are evaluated as unavailable by health rules,
+// while alerts of servers which are no longer expected get resolved.
+// Notification errors do not stop other notifications; the first one is returned.
+func (e *AlertEngine) Evaluate(t time.Time, data AlertData) ([]Alert, error) {
+	e.mu.Lock()
+	changes := make([]Alert, 0)
+	expected, removed := e.updateServers(t, data)
+	for key, alert := range e.alerts {
+		if _, ok := removed[key.server]; !ok {
+			continue
+		}
+		delete(e.alerts, key)
+		if alert.State == AlertFiring {
+			alert.State = AlertResolved
+			alert.Time = t
+			changes = append(changes, *alert)
+		}
+	}
+	for key := range e.samples {
+		if _, ok := removed[key.server]; ok {
+			delete(e.samples, key)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Rule != changes[j].Rule {
+			return changes[i].Rule < changes[j].Rule
+		}
+		return serverName(changes[i].Server) < serverName(changes[j].Server)
+	})
+
+	for i, rule := range e.rules {
+		for _, sv := range rule.values(data, expected) {
+			key := alertKey{rule: i, server: sv.server.ID}
+			value := sv.value
+			if rule.Rate {
+				prev, ok := e.samples[key]
+				e.samples[key] = Point{Time: t, Value: sv.value}
+				elapsed := t.Sub(prev.Time).Seconds()
+				// counter resets are skipped
+				if !ok || elapsed <= 0 || sv.value < prev.Value {
+					continue
+				}
+				value = (sv.value - prev.Value) / elapsed
+			}
+
+			alert, active := e.alerts[key]
+			if !rule.matches(value) {
+				if active {
+					delete(e.alerts, key)
+					if alert.State == AlertFiring {
+						alert.State = AlertResolved
+						alert.Value = value
+						alert.Time = t
+						changes = append(changes, *alert)
+					}
+				}
+				continue
+			}
+			if !active {
+				alert = &Alert{Rule: rule.Name, Expr: rule.Expr, Server: sv.server, State: AlertPending, Since: t}
+				e.alerts[key] = alert
+			}
+			alert.Value = value
+			alert.Time = t
+			if alert.State == AlertPending && t.Sub(alert.Since) >= rule.For {
+				alert.State = AlertFiring
+				changes = append(changes, *alert)
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	var err error
+	for _, alert := range changes {
+		for _, notifier := range e.notifiers {
+			if notifyErr := notifier.Notify(alert); notifyErr != nil && err == nil {
+				err = fmt.Errorf("notifying about %q on %s: %w", alert.Rule, serverName(alert.Server), notifyErr)
+			}
+		}
+	}
+	return changes, err
+}
+
+// updateServers records servers included in the data and returns servers expected to respond,
+// along with servers which are no longer expected. Servers included in the data are always expected.
+// If no server responded, e.g. because polling failed, no server is considered removed.
+func (e *AlertEngine) updateServers(t time.Time, data AlertData) (map[string]ServerInfo, map[string]struct{}) {
+	expected := make(map[string]ServerInfo)
+	seen := func(server ServerInfo) {
+		e.servers[server.ID] = seenServer{server: server, time: t}
+		expected[server.ID] = server
+	}
+	for i := range data.Varz {
+		seen(data.Varz[i].Server)
+	}
+	for i := range data.Statsz {
+		seen(data.Statsz[i].Server)
+	}
+	for i := range data.Jsz {
+		seen(data.Jsz[i].Server)
+	}
+	for i := range data.Healthz {
+		seen(data.Healthz[i].Server)
+	}
+
+	switch {
+	case len(data.Servers) > 0:
+		for _, server := range data.Servers {
+			if _, ok := expected[server.ID]; !ok {
+				expected[server.ID] = server
+			}
+		}
+	case len(expected) == 0:
+		for id, server := range e.servers {
+			expected[id] = server.server
+		}
+	default:
+		for id, server := range e.servers {
+			if t.Sub(server.time) < DefaultAlertServerTimeout {
+				expected[id] = server.server
+			}
+		}
+	}
+
+	removed := make(map[string]struct{})
+	for id := range e.servers {
+		if _, ok := expected[id]; !ok {
+			removed[id] = struct{}{}
+			delete(e.servers, id)
+		}
+	}
+	for key := range e.alerts {
+		if _, ok := expected[key.server]; !ok {
+			removed[key.server] = struct{}{}
+		}
+	}
+	return expected, removed
+}
+
+// Alerts returns pending and firing alerts, sorted by rule and server name.
+func (e *AlertEngine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, alert := range e.alerts {
+		alerts = append(alerts, *alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return serverName(alerts[i].Server) < serverName(alerts[j].Server)
+	})
+	return alerts
+}
+
+// WatchAlerts polls endpoints used by the engine rules and evaluates them until the context is done.
+// Polling and notification errors are sent on the returned channel, which is closed when the context is done.
+func WatchAlerts(ctx context.Context, client SysClient, engine *AlertEngine, interval time.Duration) <-chan error {
+	if interval <= 0 {
+		interval = DefaultAlertInterval
+	}
+	endpoints := make(map[Endpoint]struct{})
+	for _, rule := range engine.rules {
+		endpoints[rule.endpoint()] = struct{}{}
+	}
+
+	errs := make(chan error)
+	go func() {
+		defer close(errs)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			data, pollErrs := pollAlertData(client, endpoints)
+			if _, err := engine.Evaluate(time.Now().UTC(), data); err != nil {
+				pollErrs = append(pollErrs, err)
+			}
+			for _, err := range pollErrs {
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return errs
+}
+
+func pollAlertData(client SysClient, endpoints map[Endpoint]struct{}) (AlertData, []error) {
+	var data AlertData
+	var errs []error
+	var err error
+	if _, ok := endpoints[EndpointVarz]; ok {
+		if data.Varz, err = client.VarzPing(VarzEventOptions{}); err != nil {
+			errs = append(errs, fmt.Errorf("polling VARZ: %w", err))
+		}
+	}
+	if _, ok := endpoints[EndpointStatsz]; ok {
+		if data.Statsz, err = client.ServerStatszPing(StatszEventOptions{}); err != nil {
+			errs = append(errs, fmt.Errorf("polling STATSZ: %w", err))
+		}
+	}
+	if _, ok := endpoints[EndpointJsz]; ok {
+		if data.Jsz, err = client.JszPing(JszEventOptions{}); err != nil {
+			errs = append(errs, fmt.Errorf("polling JSZ: %w", err))
+		}
+	}
+	if _, ok := endpoints[EndpointHealthz]; ok {
+		if data.Healthz, err = client.HealthzPing(HealthzOptions{}); err != nil {
+			errs = append(errs, fmt.Errorf("polling HEALTHZ: %w", err))
+		}
+	}
+	return data, errs
+}
@@ -0,0 +1,414 @@
+package sys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestParseAlertRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		expr      string
+		expected  AlertRule
+		withError bool
+	}{
+		{
+			name:     "size threshold with duration",
+			expr:     "varz.mem > 2GiB for 5m",
+			expected: AlertRule{Metric: "varz.mem", Operator: ">", Threshold: 2 << 30, For: 5 * time.Minute},
+		},
+		{
+			name:     "rate",
+			expr:     "statsz.slow_consumers rate > 0",
+			expected: AlertRule{Metric: "statsz.slow_consumers", Rate: true, Operator: ">", Threshold: 0},
+		},
+		{
+			name:     "rate per minute",
+			expr:     "jsz.api.errors rate >= 120/m",
+			expected: AlertRule{Metric: "jsz.api.errors", Rate: true, Operator: ">=", Threshold: 2},
+		},
+		{
+			name:     "health status",
+			expr:     "healthz.status != ok",
+			expected: AlertRule{Metric: "healthz.status", Operator: "!=", Threshold: float64(StatusOK)},
+		},
+		{
+			name:     "decimal size",
+			expr:     "jsz.storage <= 1.5MB",
+			expected: AlertRule{Metric: "jsz.storage", Operator: "<=", Threshold: 1.5e6},
+		},
+		{name: "unknown metric", expr: "varz.unknown > 1", withError: true},
+		{name: "unknown operator", expr: "varz.mem => 1", withError: true},
+		{name: "missing threshold", expr: "varz.mem rate >", withError: true},
+		{name: "invalid threshold", expr: "varz.mem > 1XB", withError: true},
+		{name: "time unit without rate", expr: "varz.mem > 1/s", withError: true},
+		{name: "invalid duration", expr: "varz.mem > 1 for ever", withError: true},
+		{name: "trailing tokens", expr: "varz.mem > 1 for 5m now", withError: true},
+		{name: "unknown health status", expr: "healthz.status != green", withError: true},
+		{name: "health status ordering", expr: "healthz.status > ok", withError: true},
+		{name: "health status rate", expr: "healthz.status rate != ok", withError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := ParseAlertRule(test.expr)
+			if test.withError {
+				if !errors.Is(err, ErrValidation) {
+					t.Fatalf("Expected validation error; got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unable to parse rule: %s", err)
+			}
+			test.expected.Name, test.expected.Expr = test.expr, test.expr
+			if *rule != test.expected {
+				t.Fatalf("Invalid rule; want: %+v; got: %+v", test.expected, *rule)
+			}
+		})
+	}
+}
+
+type recordingNotifier struct {
+	alerts []Alert
+	err    error
+}
+
+func (n *recordingNotifier) Notify(alert Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return n.err
+}
+
+func mustParseAlertRule(t *testing.T, expr string) *AlertRule {
+	t.Helper()
+	rule, err := ParseAlertRule(expr)
+	if err != nil {
+		t.Fatalf("Unable to parse rule: %s", err)
+	}
+	return rule
+}
+
+func TestAlertEngineEvaluate(t *testing.T) {
+	s1 := ServerInfo{ID: "S1", Name: "s1"}
+	s2 := ServerInfo{ID: "S2", Name: "s2"}
+	varz := func(server ServerInfo, mem int64) VarzResp {
+		return VarzResp{Server: server, Varz: Varz{Mem: mem}}
+	}
+	statsz := func(server ServerInfo, slow int64) ServerStatszResp {
+		return ServerStatszResp{Server: server, Statsz: ServerStats{SlowConsumers: slow}}
+	}
+	healthz := func(server ServerInfo, status HealthStatus) HealthzResp {
+		return HealthzResp{Server: server, Healthz: Healthz{Status: status}}
+	}
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	notifier := &recordingNotifier{}
+	engine := NewAlertEngine([]*AlertRule{
+		mustParseAlertRule(t, "varz.mem > 1KiB for 1m"),
+		mustParseAlertRule(t, "statsz.slow_consumers rate > 1/s"),
+		mustParseAlertRule(t, "healthz.status != ok"),
+	}, notifier)
+
+	steps := []struct {
+		name     string
+		offset   time.Duration
+		data     AlertData
+		expected []Alert
+		active   int
+	}{
+		{
+			name:   "conditions start to hold",
+			offset: 0,
+			data: AlertData{
+				Varz:    []VarzResp{varz(s1, 2048), varz(s2, 100)},
+				Statsz:  []ServerStatszResp{statsz(s1, 0)},
+				Healthz: []HealthzResp{healthz(s1, StatusOK), healthz(s2, StatusUnavailable)},
+			},
+			expected: []Alert{
+				{Rule: "healthz.status != ok", Server: s2, State: AlertFiring, Value: float64(StatusUnavailable), Since: start, Time: start},
+			},
+			active: 2,
+		},
+		{
+			name:   "pending alert fires after duration",
+			offset: time.Minute,
+			data: AlertData{
+				Varz:   []VarzResp{varz(s1, 4096), varz(s2, 100)},
+				Statsz: []ServerStatszResp{statsz(s1, 120)},
+			},
+			expected: []Alert{
+				{Rule: "varz.mem > 1KiB for 1m", Server: s1, State: AlertFiring, Value: 4096, Since: start, Time: start.Add(time.Minute)},
+				{Rule: "statsz.slow_consumers rate > 1/s", Server: s1, State: AlertFiring, Value: 2, Since: start.Add(time.Minute), Time: start.Add(time.Minute)},
+			},
+			active: 3,
+		},
+		{
+			name:   "alerts get resolved",
+			offset: 2 * time.Minute,
+			data: AlertData{
+				Varz:    []VarzResp{varz(s1, 100)},
+				Statsz:  []ServerStatszResp{statsz(s1, 150)},
+				Healthz: []HealthzResp{healthz(s1, StatusOK), healthz(s2, StatusOK)},
+			},
+			expected: []Alert{
+				{Rule: "varz.mem > 1KiB for 1m", Server: s1, State: AlertResolved, Value: 100, Since: start, Time: start.Add(2 * time.Minute)},
+				{Rule: "statsz.slow_consumers rate > 1/s", Server: s1, State: AlertResolved, Value: 0.5, Since: start.Add(time.Minute), Time: start.Add(2 * time.Minute)},
+				{Rule: "healthz.status != ok", Server: s2, State: AlertResolved, Value: float64(StatusOK), Since: start, Time: start.Add(2 * time.Minute)},
+			},
+			active: 0,
+		},
+		{
+			name:     "counter reset is skipped",
+			offset:   3 * time.Minute,
+			data:     AlertData{Statsz: []ServerStatszResp{statsz(s1, 0)}},
+			expected: []Alert{},
+			active:   0,
+		},
+		{
+			name:   "missing server fails health rules",
+			offset: 4 * time.Minute,
+			data:   AlertData{Healthz: []HealthzResp{healthz(s1, StatusOK)}},
+			expected: []Alert{
+				{Rule: "healthz.status != ok", Server: s2, State: AlertFiring, Value: float64(StatusUnavailable), Since: start.Add(4 * time.Minute), Time: start.Add(4 * time.Minute)},
+			},
+			active: 1,
+		},
+		{
+			name:     "missing server stays unavailable if no server responds",
+			offset:   2*time.Minute + DefaultAlertServerTimeout,
+			data:     AlertData{},
+			expected: []Alert{},
+			active:   1,
+		},
+		{
+			name:   "alerts of removed servers get resolved",
+			offset: 2*time.Minute + DefaultAlertServerTimeout,
+			data:   AlertData{Healthz: []HealthzResp{healthz(s1, StatusOK)}},
+			expected: []Alert{
+				{Rule: "healthz.status != ok", Server: s2, State: AlertResolved, Value: float64(StatusUnavailable), Since: start.Add(4 * time.Minute), Time: start.Add(2*time.Minute + DefaultAlertServerTimeout)},
+			},
+			active: 0,
+		},
+		{
+			name:   "expected servers fail health rules",
+			offset: 3*time.Minute + DefaultAlertServerTimeout,
+			data:   AlertData{Servers: []ServerInfo{s1, s2}, Healthz: []HealthzResp{healthz(s1, StatusOK)}},
+			expected: []Alert{
+				{Rule: "healthz.status != ok", Server: s2, State: AlertFiring, Value: float64(StatusUnavailable), Since: start.Add(3*time.Minute + DefaultAlertServerTimeout), Time: start.Add(3*time.Minute + DefaultAlertServerTimeout)},
+			},
+			active: 1,
+		},
+		{
+			name:   "alerts of servers which are no longer expected get resolved",
+			offset: 4*time.Minute + DefaultAlertServerTimeout,
+			data:   AlertData{Servers: []ServerInfo{s1}, Varz: []VarzResp{varz(s1, 100)}},
+			expected: []Alert{
+				{Rule: "healthz.status != ok", Server: s2, State: AlertResolved, Value: float64(StatusUnavailable), Since: start.Add(3*time.Minute + DefaultAlertServerTimeout), Time: start.Add(4*time.Minute + DefaultAlertServerTimeout)},
+			},
+			active: 0,
+		},
+	}
+
+	var notified int
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			changes, err := engine.Evaluate(start.Add(step.offset), step.data)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			for i := range step.expected {
+				step.expected[i].Expr = step.expected[i].Rule
+			}
+			if len(changes) != len(step.expected) {
+				t.Fatalf("Invalid changes; want: %+v; got: %+v", step.expected, changes)
+			}
+			for i := range changes {
+				if !reflect.DeepEqual(changes[i], step.expected[i]) {
+					t.Fatalf("Invalid change; want: %+v; got: %+v", step.expected[i], changes[i])
+				}
+			}
+			notified += len(changes)
+			if len(notifier.alerts) != notified {
+				t.Fatalf("Invalid number of notifications; want: %d; got: %d", notified, len(notifier.alerts))
+			}
+			if active := engine.Alerts(); len(active) != step.active {
+				t.Fatalf("Invalid number of active alerts; want: %d; got: %+v", step.active, active)
+			}
+		})
+	}
+}
+
+func TestAlertEngineNotifyError(t *testing.T) {
+	failing := &recordingNotifier{err: errors.New("unavailable")}
+	other := &recordingNotifier{}
+	engine := NewAlertEngine([]*AlertRule{mustParseAlertRule(t, "varz.connections >= 0")}, failing, other)
+
+	changes, err := engine.Evaluate(time.Now(), AlertData{Varz: []VarzResp{{Server: ServerInfo{ID: "S1"}}}})
+	if err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Fatalf("Expected notification error; got: %v", err)
+	}
+	if len(changes) != 1 || len(other.alerts) != 1 {
+		t.Fatalf("Expected alert to be returned and notified; got: %+v", changes)
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	alert := Alert{
+		Rule:   "varz.mem > 1KiB",
+		Expr:   "varz.mem > 1KiB",
+		Server: ServerInfo{ID: "S1", Name: "s1"},
+		State:  AlertFiring,
+		Value:  2048,
+		Since:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Time:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("log", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewLogNotifier(log.New(&buf, "", 0)).Notify(alert); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		expected := "[firing] varz.mem > 1KiB on s1 (value: 2048, since: 2023-01-01T00:00:00Z)\n"
+		if buf.String() != expected {
+			t.Fatalf("Invalid log; want: %q; got: %q", expected, buf.String())
+		}
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		received := make(chan Alert, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var a Alert
+			if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			received <- a
+		}))
+		defer srv.Close()
+
+		if err := NewWebhookNotifier(srv.URL, nil).Notify(alert); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if a := <-received; a.Rule != alert.Rule || a.State != alert.State || a.Server.ID != "S1" {
+			t.Fatalf("Invalid alert; want: %+v; got: %+v", alert, a)
+		}
+	})
+
+	t.Run("webhook error status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		if err := NewWebhookNotifier(srv.URL, nil).Notify(alert); err == nil {
+			t.Fatalf("Expected error")
+		}
+	})
+
+	t.Run("webhook timeout", func(t *testing.T) {
+		done := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+		}))
+		defer srv.Close()
+		defer close(done)
+
+		if notifier := NewWebhookNotifier(srv.URL, nil); notifier.client.Timeout != DefaultWebhookTimeout {
+			t.Fatalf("Invalid default timeout; want: %s; got: %s", DefaultWebhookTimeout, notifier.client.Timeout)
+		}
+		if err := NewWebhookNotifier(srv.URL, &http.Client{Timeout: 50 * time.Millisecond}).Notify(alert); err == nil {
+			t.Fatalf("Expected error")
+		}
+	})
+
+	t.Run("nats", func(t *testing.T) {
+		c := SetupCluster(t)
+		defer c.Shutdown()
+
+		nc, err := nats.Connect(c.servers[0].ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+		if err != nil {
+			t.Fatalf("Error establishing connection: %s", err)
+		}
+		defer nc.Close()
+
+		sub, err := nc.SubscribeSync("alerts")
+		if err != nil {
+			t.Fatalf("Unable to subscribe: %s", err)
+		}
+		if err := NewNATSNotifier(nc, "alerts").Notify(alert); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		msg, err := sub.NextMsg(time.Second)
+		if err != nil {
+			t.Fatalf("Notification not received: %s", err)
+		}
+		var a Alert
+		if err := json.Unmarshal(msg.Data, &a); err != nil {
+			t.Fatalf("Unable to unmarshal alert: %s", err)
+		}
+		if a.Rule != alert.Rule || a.Value != alert.Value {
+			t.Fatalf("Invalid alert; want: %+v; got: %+v", alert, a)
+		}
+	})
+}
+
+func TestWatchAlerts(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	notifier := &recordingNotifier{}
+	engine := NewAlertEngine([]*AlertRule{
+		mustParseAlertRule(t, "varz.routes < 2"),
+		mustParseAlertRule(t, "healthz.status != ok"),
+		mustParseAlertRule(t, "jsz.max_storage > 0"),
+	}, notifier)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	for err := range WatchAlerts(ctx, sys, engine, 100*time.Millisecond) {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// only the JetStream storage limit is exceeded on each server
+	alerts := engine.Alerts()
+	if len(alerts) != 3 {
+		t.Fatalf("Invalid number of alerts; want: %d; got: %+v", 3, alerts)
+	}
+	for _, alert := range alerts {
+		if alert.Rule != "jsz.max_storage > 0" || alert.State != AlertFiring {
+			t.Fatalf("Invalid alert: %+v", alert)
+		}
+	}
+	if len(notifier.alerts) != 3 {
+		t.Fatalf("Invalid number of notifications; want: %d; got: %d", 3, len(notifier.alerts))
+	}
+}
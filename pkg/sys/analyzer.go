@@ -0,0 +1,90 @@
+package sys
+
+import "context"
+
+// Analyzer builds reports, such as ClusterHealth or StreamHealth, from monitoring data of any SysClient.
+// This allows running the same analyses on a live System, a CachedClient, a SnapshotSource or a fake client.
+// Analyses are also available as System methods, which use an Analyzer on the system client.
+type Analyzer struct {
+	client SysClient
+}
+
+// NewAnalyzer creates an Analyzer requesting monitoring data from the given client.
+func NewAnalyzer(client SysClient) *Analyzer {
+	return &Analyzer{client: client}
+}
+
+// ClusterHealth returns Analyzer.ClusterHealth of the system.
+func (s *System) ClusterHealth(opts ClusterHealthOptions) (*ClusterHealthReport, error) {
+	return NewAnalyzer(s).ClusterHealth(opts)
+}
+
+// StreamHealth returns Analyzer.StreamHealth of the system.
+func (s *System) StreamHealth(opts StreamHealthOptions) (*StreamHealthReport, error) {
+	return NewAnalyzer(s).StreamHealth(opts)
+}
+
+// DriftReport returns Analyzer.DriftReport of the system.
+func (s *System) DriftReport(opts DriftOptions) (*DriftReport, error) {
+	return NewAnalyzer(s).DriftReport(opts)
+}
+
+// CanRestart returns Analyzer.CanRestart of the system.
+func (s *System) CanRestart(serverName string) (*RestartVerdict, error) {
+	return NewAnalyzer(s).CanRestart(serverName)
+}
+
+// WhoReceives returns Analyzer.WhoReceives of the system.
+func (s *System) WhoReceives(account, subject string) (*InterestReport, error) {
+	return NewAnalyzer(s).WhoReceives(account, subject)
+}
+
+// SubjectTree returns Analyzer.SubjectTree of the system.
+func (s *System) SubjectTree(account string) (*SubjectTree, error) {
+	return NewAnalyzer(s).SubjectTree(account)
+}
+
+// Topology returns Analyzer.Topology of the system.
+func (s *System) Topology() (*Topology, error) {
+	return NewAnalyzer(s).Topology()
+}
+
+// JetStreamCapacity returns Analyzer.JetStreamCapacity of the system.
+func (s *System) JetStreamCapacity(opts JetStreamCapacityOptions) (*JetStreamCapacityReport, error) {
+	return NewAnalyzer(s).JetStreamCapacity(opts)
+}
+
+// ConsumerLag returns Analyzer.ConsumerLag of the system.
+func (s *System) ConsumerLag(opts ConsumerLagOptions) (*ConsumerLagReport, error) {
+	return NewAnalyzer(s).ConsumerLag(opts)
+}
+
+// WatchConsumerLag returns Analyzer.WatchConsumerLag of the system.
+func (s *System) WatchConsumerLag(ctx context.Context, opts ConsumerLagOptions) <-chan ConsumerLagEvent {
+	return NewAnalyzer(s).WatchConsumerLag(ctx, opts)
+}
+
+// ClientInventory returns Analyzer.ClientInventory of the system.
+func (s *System) ClientInventory(opts ClientInventoryOptions) (*ClientInventory, error) {
+	return NewAnalyzer(s).ClientInventory(opts)
+}
+
+// RecentDisconnects returns Analyzer.RecentDisconnects of the system.
+func (s *System) RecentDisconnects(opts DisconnectOptions) (*DisconnectReport, error) {
+	return NewAnalyzer(s).RecentDisconnects(opts)
+}
+
+// FindSlowConsumers returns Analyzer.FindSlowConsumers of the system.
+func (s *System) FindSlowConsumers(opts SlowConsumerOptions) (*SlowConsumerReport, error) {
+	return NewAnalyzer(s).FindSlowConsumers(opts)
+}
+
+// WatchSlowConsumers returns Analyzer.WatchSlowConsumers of the system.
+func (s *System) WatchSlowConsumers(ctx context.Context, opts SlowConsumerOptions) <-chan SlowConsumerEvent {
+	return NewAnalyzer(s).WatchSlowConsumers(ctx, opts)
+}
+
+// CaptureSnapshot returns Analyzer.CaptureSnapshot of the system.
+func (s *System) CaptureSnapshot(opts SnapshotOptions) (*Snapshot, error) {
+	return NewAnalyzer(s).CaptureSnapshot(opts)
+}
@@ -0,0 +1,167 @@
+package sys
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestAnalyzerSnapshotReplay(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	nc, err := nats.Connect(strings.Join(urls, ","))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer nc.Close()
+	if _, err := nc.SubscribeSync("events.*"); err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	closed, err := nats.Connect(urls[0], nats.Name("closed"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	closed.Close()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Error getting JetStream context: %v", err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}, Replicas: 3}); err != nil {
+		t.Fatalf("Error creating stream: %v", err)
+	}
+	if _, err := js.AddConsumer("ORDERS", &nats.ConsumerConfig{Durable: "c1", AckPolicy: nats.AckExplicitPolicy}); err != nil {
+		t.Fatalf("Error creating consumer: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := js.Publish("orders.new", []byte("hello")); err != nil {
+			t.Fatalf("Error publishing message: %v", err)
+		}
+	}
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	// replicas may need a moment to become current
+	timeout := time.Now().Add(5 * time.Second)
+	for time.Now().Before(timeout) {
+		report, err := sys.StreamHealth(StreamHealthOptions{Samples: 1})
+		if err != nil {
+			t.Fatalf("Unable to fetch stream health: %s", err)
+		}
+		if report.Status == StatusOK {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	snapshot, err := sys.CaptureSnapshot(SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("Unable to capture snapshot: %s", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := snapshot.Write(buf); err != nil {
+		t.Fatalf("Unable to write snapshot: %s", err)
+	}
+	restored, err := ReadSnapshot(buf)
+	if err != nil {
+		t.Fatalf("Unable to read snapshot: %s", err)
+	}
+	if restored.ExpectedServers != 3 {
+		t.Fatalf("Invalid number of expected servers: %d; want: %d", restored.ExpectedServers, 3)
+	}
+	cached, err := NewCachedClient(NewSnapshotSource(restored))
+	if err != nil {
+		t.Fatalf("Unable to create cached client: %s", err)
+	}
+
+	for name, client := range map[string]SysClient{
+		"snapshot": NewSnapshotSource(restored),
+		"cached":   cached,
+	} {
+		t.Run(name, func(t *testing.T) {
+			analyzer := NewAnalyzer(client)
+			health, err := analyzer.ClusterHealth(ClusterHealthOptions{})
+			if err != nil {
+				t.Fatalf("Unable to check cluster health: %s", err)
+			}
+			if health.ServersResponding != 3 || health.MetaLeader == "" {
+				t.Fatalf("Invalid cluster health: %+v", health)
+			}
+			drift, err := analyzer.DriftReport(DriftOptions{})
+			if err != nil {
+				t.Fatalf("Unable to fetch drift report: %s", err)
+			}
+			if drift.Servers != 3 || drift.Drifted {
+				t.Fatalf("Invalid drift report: %+v", drift)
+			}
+			topology, err := analyzer.Topology()
+			if err != nil {
+				t.Fatalf("Unable to build topology: %s", err)
+			}
+			if len(topology.Nodes) != 3 {
+				t.Fatalf("Invalid number of topology nodes: %d; want: %d", len(topology.Nodes), 3)
+			}
+			verdict, err := analyzer.CanRestart(c.servers[0].Name())
+			if err != nil {
+				t.Fatalf("Unable to check restart: %s", err)
+			}
+			if !verdict.Safe {
+				t.Fatalf("Expected restart to be safe: %+v", verdict)
+			}
+
+			streams, err := analyzer.StreamHealth(StreamHealthOptions{})
+			if err != nil {
+				t.Fatalf("Unable to check stream health: %s", err)
+			}
+			if streams.Status != StatusOK || len(streams.Streams) != 1 {
+				t.Fatalf("Invalid stream health: %+v", streams)
+			}
+			if stream := streams.Streams[0]; stream.Name != "ORDERS" || stream.Account != "JS" || stream.Replicas != 3 || stream.Peers != 3 || stream.Leader == "" {
+				t.Fatalf("Invalid stream health: %+v", stream)
+			}
+			lag, err := analyzer.ConsumerLag(ConsumerLagOptions{})
+			if err != nil {
+				t.Fatalf("Unable to fetch consumer lag: %s", err)
+			}
+			if len(lag.Consumers) != 1 {
+				t.Fatalf("Invalid number of consumers: %d; want: %d", len(lag.Consumers), 1)
+			}
+			if consumer := lag.Consumers[0]; consumer.Stream != "ORDERS" || consumer.Consumer != "c1" || consumer.Lag != 10 || consumer.Leader == "" {
+				t.Fatalf("Invalid consumer lag: %+v", consumer)
+			}
+			tree, err := analyzer.SubjectTree("JS")
+			if err != nil {
+				t.Fatalf("Unable to build subject tree: %s", err)
+			}
+			if node := tree.Find("events.*"); node == nil || node.Stats.Subscribers != 1 {
+				t.Fatalf("Invalid subject tree node: %+v", node)
+			}
+			disconnects, err := analyzer.RecentDisconnects(DisconnectOptions{Account: "JS"})
+			if err != nil {
+				t.Fatalf("Unable to fetch disconnects: %s", err)
+			}
+			var found bool
+			for _, group := range disconnects.ByClient {
+				found = found || (group.Key == "closed@127.0.0.1" && group.Count == 1)
+			}
+			if !found {
+				t.Fatalf("Closed connection not reported: %+v", disconnects.ByClient)
+			}
+		})
+	}
+}
@@ -22,6 +22,19 @@ const (
 	srvJszSubj     = "$SYS.REQ.SERVER.%s.JSZ"
 )
 
+// Endpoint identifies a monitoring endpoint.
+type Endpoint string
+
+// Possible endpoints
+const (
+	EndpointVarz    Endpoint = "VARZ"
+	EndpointConnz   Endpoint = "CONNZ"
+	EndpointSubsz   Endpoint = "SUBSZ"
+	EndpointJsz     Endpoint = "JSZ"
+	EndpointStatsz  Endpoint = "STATSZ"
+	EndpointHealthz Endpoint = "HEALTHZ"
+)
+
 var (
 	ErrValidation      = errors.New("validation error")
 	ErrInvalidServerID = errors.New("sever with given ID does not exist")
@@ -29,16 +42,56 @@ var (
 
 // System can be used to request monitoring data from the server
 type System struct {
-	nc   *nats.Conn
-	opts *sysClientOpts
+	nc       *nats.Conn
+	opts     *sysClientOpts
+	resolver *serverResolver
+	servers  *serverCounter
+}
+
+// SysClient contains all monitoring endpoint methods.
+// It is implemented by System and SnapshotSource and allows replacing System with a fake in tests.
+// Analyzer runs analyses, such as ClusterHealth, on top of any SysClient.
+type SysClient interface {
+	Varz(id string, opts VarzEventOptions) (*VarzResp, error)
+	VarzPing(opts VarzEventOptions) ([]VarzResp, error)
+	Connz(id string, opts ConnzEventOptions) (*ConnzResp, error)
+	ConnzPing(opts ConnzEventOptions) ([]ConnzResp, error)
+	ServerSubsz(id string, opts SubszOptions) (*SubszResp, error)
+	ServerSubszPing(opts SubszOptions) ([]SubszResp, error)
+	Jsz(id string, opts JszEventOptions) (*JSZResp, error)
+	JszPing(opts JszEventOptions) ([]JSZResp, error)
+	ServerStatsz(id string, opts StatszEventOptions) (*ServerStatszResp, error)
+	ServerStatszPing(opts StatszEventOptions) ([]ServerStatszResp, error)
+	Healthz(id string, opts HealthzOptions) (*HealthzResp, error)
+	HealthzPing(opts HealthzOptions) ([]HealthzResp, error)
 }
 
+// ServerCountProvider is implemented by clients which know how many servers are expected to respond,
+// so that analyses can report missing servers.
+type ServerCountProvider interface {
+	// ExpectedServers returns the number of servers expected to respond or 0 if it is not known.
+	ExpectedServers() int
+}
+
+var (
+	_ SysClient = (*System)(nil)
+	_ SysClient = (*SnapshotSource)(nil)
+
+	_ ServerCountProvider = (*System)(nil)
+	_ ServerCountProvider = (*CachedClient)(nil)
+	_ ServerCountProvider = (*SnapshotSource)(nil)
+)
+
 type SysClientOpt func(*sysClientOpts) error
 
 type sysClientOpts struct {
 	timeout              time.Duration
 	multiRequestInterval time.Duration
 	serverCount          int
+	allowControl         bool
+	serverCacheTTL       time.Duration
+	retry                *RetryPolicy
+	autoServerCount      bool
 }
 
 func SysRequestTimeout(timeout time.Duration) SysClientOpt {
@@ -94,22 +147,35 @@ func NewSysClient(nc *nats.Conn, opts ...SysClientOpt) (*System, error) {
 		timeout:              DefaultRequestTimeout,
 		multiRequestInterval: DefaultRequestInterval,
 		serverCount:          -1,
+		serverCacheTTL:       DefaultServerCacheTTL,
 	}
 	for _, opt := range opts {
 		if err := opt(sysOpts); err != nil {
 			return nil, err
 		}
 	}
-	return &System{
-		nc:   nc,
-		opts: sysOpts,
-	}, nil
+	sys := &System{
+		nc:      nc,
+		opts:    sysOpts,
+		servers: &serverCounter{ttl: sysOpts.serverCacheTTL},
+	}
+	sys.resolver = sys.newServerResolver()
+	return sys, nil
+}
+
+// ExpectedServers returns the number of servers set with ServerCount option or 0 if it is not set.
+func (s *System) ExpectedServers() int {
+	if s.opts.serverCount > 0 {
+		return s.opts.serverCount
+	}
+	return 0
 }
 
 type requestManyOpts struct {
 	maxWait     time.Duration
 	maxInterval time.Duration
 	count       int
+	autoCount   bool // count is the number of servers learned with AutoServerCount
 }
 
 type RequestManyOpt func(*requestManyOpts) error
@@ -149,7 +215,6 @@ func (s *System) RequestMany(subject string, data []byte, opts ...RequestManyOpt
 		return nil, fmt.Errorf("%w: subject cannot be empty", ErrValidation)
 	}
 
-	conn := s.nc
 	reqOpts := &requestManyOpts{
 		maxWait:     s.opts.timeout,
 		maxInterval: s.opts.multiRequestInterval,
@@ -161,7 +226,22 @@ func (s *System) RequestMany(subject string, data []byte, opts ...RequestManyOpt
 			return nil, err
 		}
 	}
+	if reqOpts.count == -1 && s.opts.autoServerCount {
+		reqOpts.count = s.knownServerCount(subject)
+		reqOpts.autoCount = reqOpts.count != -1
+	}
 
+	var res []*nats.Msg
+	err := s.withRetry(func() error {
+		var err error
+		res, err = s.requestMany(subject, data, reqOpts)
+		return err
+	})
+	return res, err
+}
+
+func (s *System) requestMany(subject string, data []byte, reqOpts *requestManyOpts) ([]*nats.Msg, error) {
+	conn := s.nc
 	inbox := nats.NewInbox()
 	res := make([]*nats.Msg, 0)
 	msgsChan := make(chan *nats.Msg, 100)
@@ -177,7 +257,12 @@ func (s *System) RequestMany(subject string, data []byte, opts ...RequestManyOpt
 	if err != nil {
 		return nil, err
 	}
-	defer sub.Unsubscribe()
+	var awaitExtra bool
+	defer func() {
+		if !awaitExtra {
+			sub.Unsubscribe()
+		}
+	}()
 
 	if err := conn.PublishRequest(subject, inbox, data); err != nil {
 		return nil, err
@@ -191,6 +276,10 @@ func (s *System) RequestMany(subject string, data []byte, opts ...RequestManyOpt
 			}
 			res = append(res, msg)
 			if reqOpts.count != -1 && len(res) == reqOpts.count {
+				if reqOpts.autoCount && reqOpts.maxInterval > 0 {
+					awaitExtra = true
+					go s.awaitExtraResponses(sub, msgsChan, timer)
+				}
 				return res, nil
 			}
 		case <-timer.C:
@@ -0,0 +1,335 @@
+package sys
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const DefaultCacheTTL = 5 * time.Second
+
+type (
+	// CachedClient is a SysClient caching responses of the wrapped client.
+	//
+	// Responses are cached by request subject and options, separately for each endpoint.
+	// Concurrent identical requests are de-duplicated, so only one of them reaches the wrapped client.
+	// Errors are not cached. Cached responses are shared between callers and must not be modified.
+	CachedClient struct {
+		client  SysClient
+		opts    *cacheOpts
+		mu      sync.Mutex
+		entries map[string]*cacheEntry
+		calls   map[string]*cacheCall
+		stats   CacheStats
+	}
+
+	// CacheStats contains cache counters.
+	CacheStats struct {
+		Hits      uint64 `json:"hits"`       // responses served from cache
+		StaleHits uint64 `json:"stale_hits"` // expired responses served while being refreshed
+		Misses    uint64 `json:"misses"`     // requests sent to the wrapped client
+		Shared    uint64 `json:"shared"`     // requests which waited for an identical request in flight
+		Refreshes uint64 `json:"refreshes"`  // background refreshes of stale responses
+		Errors    uint64 `json:"errors"`     // failed requests to the wrapped client
+		Entries   int    `json:"entries"`    // number of cached responses
+	}
+
+	CacheOpt func(*cacheOpts) error
+
+	cacheOpts struct {
+		ttl                  time.Duration
+		endpointTTL          map[Endpoint]time.Duration
+		staleWhileRevalidate time.Duration
+	}
+
+	cacheEntry struct {
+		value      interface{}
+		expiresAt  time.Time
+		staleUntil time.Time
+	}
+
+	// cacheCall is a request in flight, awaited by all identical requests.
+	cacheCall struct {
+		wg    sync.WaitGroup
+		value interface{}
+		err   error
+	}
+)
+
+var _ SysClient = (*CachedClient)(nil)
+
+// CacheTTL sets the time for which responses are cached, DefaultCacheTTL by default.
+func CacheTTL(ttl time.Duration) CacheOpt {
+	return func(opts *cacheOpts) error {
+		if ttl <= 0 {
+			return fmt.Errorf("%w: TTL has to be greater than 0", ErrValidation)
+		}
+		opts.ttl = ttl
+		return nil
+	}
+}
+
+// EndpointCacheTTL overrides cache TTL for an endpoint.
+// TTL of 0 disables caching for the endpoint, but concurrent identical requests are still de-duplicated.
+func EndpointCacheTTL(endpoint Endpoint, ttl time.Duration) CacheOpt {
+	return func(opts *cacheOpts) error {
+		if ttl < 0 {
+			return fmt.Errorf("%w: TTL cannot be negative", ErrValidation)
+		}
+		opts.endpointTTL[endpoint] = ttl
+		return nil
+	}
+}
+
+// StaleWhileRevalidate allows serving expired responses for up to given time after expiration.
+// A stale response is returned immediately, while the cache is refreshed in the background.
+func StaleWhileRevalidate(stale time.Duration) CacheOpt {
+	return func(opts *cacheOpts) error {
+		if stale <= 0 {
+			return fmt.Errorf("%w: stale time has to be greater than 0", ErrValidation)
+		}
+		opts.staleWhileRevalidate = stale
+		return nil
+	}
+}
+
+func NewCachedClient(client SysClient, opts ...CacheOpt) (*CachedClient, error) {
+	cacheOpts := &cacheOpts{
+		ttl:         DefaultCacheTTL,
+		endpointTTL: make(map[Endpoint]time.Duration),
+	}
+	for _, opt := range opts {
+		if err := opt(cacheOpts); err != nil {
+			return nil, err
+		}
+	}
+	return &CachedClient{
+		client:  client,
+		opts:    cacheOpts,
+		entries: make(map[string]*cacheEntry),
+		calls:   make(map[string]*cacheCall),
+	}, nil
+}
+
+// Stats returns cache counters.
+func (c *CachedClient) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// ExpectedServers returns the number of servers expected by the wrapped client,
+// or 0 if it does not implement ServerCountProvider.
+func (c *CachedClient) ExpectedServers() int {
+	if provider, ok := c.client.(ServerCountProvider); ok {
+		return provider.ExpectedServers()
+	}
+	return 0
+}
+
+// Invalidate removes all cached responses.
+func (c *CachedClient) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+}
+
+func (c *CachedClient) ttl(endpoint Endpoint) time.Duration {
+	if ttl, ok := c.opts.endpointTTL[endpoint]; ok {
+		return ttl
+	}
+	return c.opts.ttl
+}
+
+// get returns cached response for the request or calls fetch.
+func (c *CachedClient) get(endpoint Endpoint, subject string, opts interface{}, fetch func() (interface{}, error)) (interface{}, error) {
+	payload, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	key := subject + " " + string(payload)
+	ttl := c.ttl(endpoint)
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		now := time.Now()
+		if now.Before(entry.expiresAt) {
+			c.stats.Hits++
+			c.mu.Unlock()
+			return entry.value, nil
+		}
+		if now.Before(entry.staleUntil) {
+			c.stats.StaleHits++
+			if _, ok := c.calls[key]; !ok {
+				c.stats.Refreshes++
+				call := c.startCall(key)
+				go c.finishCall(key, call, ttl, fetch)
+			}
+			c.mu.Unlock()
+			return entry.value, nil
+		}
+		delete(c.entries, key)
+	}
+	if call, ok := c.calls[key]; ok {
+		c.stats.Shared++
+		c.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err
+	}
+	call := c.startCall(key)
+	c.mu.Unlock()
+
+	c.finishCall(key, call, ttl, fetch)
+	return call.value, call.err
+}
+
+// startCall registers a request in flight. It has to be called with the lock held.
+func (c *CachedClient) startCall(key string) *cacheCall {
+	c.stats.Misses++
+	call := &cacheCall{}
+	call.wg.Add(1)
+	c.calls[key] = call
+	return call
+}
+
+func (c *CachedClient) finishCall(key string, call *cacheCall, ttl time.Duration, fetch func() (interface{}, error)) {
+	call.value, call.err = fetch()
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	if call.err != nil {
+		c.stats.Errors++
+	} else if ttl > 0 {
+		now := time.Now()
+		c.entries[key] = &cacheEntry{
+			value:      call.value,
+			expiresAt:  now.Add(ttl),
+			staleUntil: now.Add(ttl + c.opts.staleWhileRevalidate),
+		}
+	}
+	c.mu.Unlock()
+	call.wg.Done()
+}
+
+func (c *CachedClient) Varz(id string, opts VarzEventOptions) (*VarzResp, error) {
+	res, err := c.get(EndpointVarz, fmt.Sprintf(srvVarzSubj, id), opts, func() (interface{}, error) {
+		return c.client.Varz(id, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*VarzResp), nil
+}
+
+func (c *CachedClient) VarzPing(opts VarzEventOptions) ([]VarzResp, error) {
+	res, err := c.get(EndpointVarz, fmt.Sprintf(srvVarzSubj, "PING"), opts, func() (interface{}, error) {
+		return c.client.VarzPing(opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]VarzResp), nil
+}
+
+func (c *CachedClient) Connz(id string, opts ConnzEventOptions) (*ConnzResp, error) {
+	res, err := c.get(EndpointConnz, fmt.Sprintf(srvConnzSubj, id), opts, func() (interface{}, error) {
+		return c.client.Connz(id, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*ConnzResp), nil
+}
+
+func (c *CachedClient) ConnzPing(opts ConnzEventOptions) ([]ConnzResp, error) {
+	res, err := c.get(EndpointConnz, fmt.Sprintf(srvConnzSubj, "PING"), opts, func() (interface{}, error) {
+		return c.client.ConnzPing(opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]ConnzResp), nil
+}
+
+func (c *CachedClient) ServerSubsz(id string, opts SubszOptions) (*SubszResp, error) {
+	res, err := c.get(EndpointSubsz, fmt.Sprintf(srvSubszSubj, id), opts, func() (interface{}, error) {
+		return c.client.ServerSubsz(id, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*SubszResp), nil
+}
+
+func (c *CachedClient) ServerSubszPing(opts SubszOptions) ([]SubszResp, error) {
+	res, err := c.get(EndpointSubsz, fmt.Sprintf(srvSubszSubj, "PING"), opts, func() (interface{}, error) {
+		return c.client.ServerSubszPing(opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]SubszResp), nil
+}
+
+func (c *CachedClient) Jsz(id string, opts JszEventOptions) (*JSZResp, error) {
+	res, err := c.get(EndpointJsz, fmt.Sprintf(srvJszSubj, id), opts, func() (interface{}, error) {
+		return c.client.Jsz(id, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*JSZResp), nil
+}
+
+func (c *CachedClient) JszPing(opts JszEventOptions) ([]JSZResp, error) {
+	res, err := c.get(EndpointJsz, fmt.Sprintf(srvJszSubj, "PING"), opts, func() (interface{}, error) {
+		return c.client.JszPing(opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]JSZResp), nil
+}
+
+func (c *CachedClient) ServerStatsz(id string, opts StatszEventOptions) (*ServerStatszResp, error) {
+	res, err := c.get(EndpointStatsz, fmt.Sprintf(srvStatszSubj, id), opts, func() (interface{}, error) {
+		return c.client.ServerStatsz(id, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*ServerStatszResp), nil
+}
+
+func (c *CachedClient) ServerStatszPing(opts StatszEventOptions) ([]ServerStatszResp, error) {
+	res, err := c.get(EndpointStatsz, fmt.Sprintf(srvStatszSubj, "PING"), opts, func() (interface{}, error) {
+		return c.client.ServerStatszPing(opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]ServerStatszResp), nil
+}
+
+func (c *CachedClient) Healthz(id string, opts HealthzOptions) (*HealthzResp, error) {
+	res, err := c.get(EndpointHealthz, fmt.Sprintf(srvHealthzSubj, id), opts, func() (interface{}, error) {
+		return c.client.Healthz(id, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*HealthzResp), nil
+}
+
+func (c *CachedClient) HealthzPing(opts HealthzOptions) ([]HealthzResp, error) {
+	res, err := c.get(EndpointHealthz, fmt.Sprintf(srvHealthzSubj, "PING"), opts, func() (interface{}, error) {
+		return c.client.HealthzPing(opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]HealthzResp), nil
+}
@@ -0,0 +1,174 @@
+package sys
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingClient serves VARZ responses, counting the requests.
+// Other endpoints are not implemented.
+type countingClient struct {
+	SysClient
+	calls   int32
+	latency time.Duration
+	err     error
+}
+
+func (c *countingClient) Varz(id string, _ VarzEventOptions) (*VarzResp, error) {
+	n := atomic.AddInt32(&c.calls, 1)
+	time.Sleep(c.latency)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &VarzResp{Server: ServerInfo{ID: id, Seq: uint64(n)}}, nil
+}
+
+func (c *countingClient) VarzPing(_ VarzEventOptions) ([]VarzResp, error) {
+	n := atomic.AddInt32(&c.calls, 1)
+	time.Sleep(c.latency)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return []VarzResp{{Server: ServerInfo{ID: "S1", Seq: uint64(n)}}}, nil
+}
+
+func TestCachedClient(t *testing.T) {
+	client := &countingClient{}
+	cache, err := NewCachedClient(client, CacheTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("Unable to create cache: %s", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := cache.Varz("S1", VarzEventOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if resp.Server.Seq != 1 {
+			t.Fatalf("Expected cached response; got: %+v", resp.Server)
+		}
+	}
+	// different options and servers are cached separately
+	if _, err := cache.Varz("S1", VarzEventOptions{EventFilterOptions: EventFilterOptions{Cluster: "C1"}}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := cache.Varz("S2", VarzEventOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := cache.VarzPing(VarzEventOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := CacheStats{Hits: 2, Misses: 4, Entries: 4}
+	if stats := cache.Stats(); stats != expected {
+		t.Fatalf("Invalid stats; want: %+v; got: %+v", expected, stats)
+	}
+
+	cache.Invalidate()
+	resp, err := cache.Varz("S1", VarzEventOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if resp.Server.Seq != 5 {
+		t.Fatalf("Expected fresh response; got: %+v", resp.Server)
+	}
+}
+
+func TestCachedClientSingleflight(t *testing.T) {
+	client := &countingClient{latency: 100 * time.Millisecond}
+	cache, err := NewCachedClient(client)
+	if err != nil {
+		t.Fatalf("Unable to create cache: %s", err)
+	}
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.VarzPing(VarzEventOptions{}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if client.calls != 1 {
+		t.Fatalf("Invalid number of requests: %d; want: %d", client.calls, 1)
+	}
+	stats := cache.Stats()
+	if stats.Misses != 1 || stats.Shared+stats.Hits != 9 {
+		t.Fatalf("Invalid stats: %+v", stats)
+	}
+}
+
+func TestCachedClientStaleWhileRevalidate(t *testing.T) {
+	client := &countingClient{latency: 20 * time.Millisecond}
+	cache, err := NewCachedClient(client,
+		EndpointCacheTTL(EndpointVarz, 200*time.Millisecond),
+		StaleWhileRevalidate(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Unable to create cache: %s", err)
+	}
+
+	if _, err := cache.Varz("S1", VarzEventOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	time.Sleep(210 * time.Millisecond)
+
+	// stale response is returned without waiting for the refresh
+	start := time.Now()
+	resp, err := cache.Varz("S1", VarzEventOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if resp.Server.Seq != 1 || time.Since(start) >= client.latency {
+		t.Fatalf("Expected stale response to be returned immediately")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	resp, err = cache.Varz("S1", VarzEventOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if resp.Server.Seq != 2 {
+		t.Fatalf("Expected refreshed response; got: %+v", resp.Server)
+	}
+	stats := cache.Stats()
+	if stats.StaleHits != 1 || stats.Refreshes != 1 || stats.Hits != 1 {
+		t.Fatalf("Invalid stats: %+v", stats)
+	}
+}
+
+func TestCachedClientErrors(t *testing.T) {
+	errTest := errors.New("test error")
+	client := &countingClient{err: errTest}
+	cache, err := NewCachedClient(client)
+	if err != nil {
+		t.Fatalf("Unable to create cache: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Varz("S1", VarzEventOptions{}); !errors.Is(err, errTest) {
+			t.Fatalf("Expected error: %s; got: %v", errTest, err)
+		}
+	}
+	if stats := cache.Stats(); stats.Errors != 2 || stats.Entries != 0 {
+		t.Fatalf("Invalid stats: %+v", stats)
+	}
+
+	if _, err := NewCachedClient(client, CacheTTL(0)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected error: %s; got: %v", ErrValidation, err)
+	}
+	if _, err := NewCachedClient(client, EndpointCacheTTL(EndpointJsz, -time.Second)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected error: %s; got: %v", ErrValidation, err)
+	}
+}
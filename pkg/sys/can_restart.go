@@ -0,0 +1,262 @@
+package sys
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ErrNoMetaLeader is returned if JetStream is clustered, but the meta leader did not respond.
+// Only the meta leader reports meta group replicas, so e.g. quorum of the meta group cannot be verified without it.
+var ErrNoMetaLeader = errors.New("JetStream meta leader did not respond")
+
+type (
+	// RestartVerdict tells whether a server can be restarted without JetStream raft groups losing quorum.
+	RestartVerdict struct {
+		Server string            `json:"server"`
+		Safe   bool              `json:"safe"`
+		Groups []RaftGroupImpact `json:"groups,omitempty"`
+	}
+
+	// RaftGroupImpact describes how restarting a server affects a single raft group.
+	// Only groups which would lose quorum or whose leader is on the restarted server are reported.
+	RaftGroupImpact struct {
+		Kind        RaftGroupKind `json:"kind"`
+		Account     string        `json:"account,omitempty"`
+		Stream      string        `json:"stream,omitempty"`
+		Consumer    string        `json:"consumer,omitempty"`
+		RaftGroup   string        `json:"raft_group,omitempty"`
+		Size        int           `json:"size"`
+		Healthy     int           `json:"healthy"`
+		LosesQuorum bool          `json:"loses_quorum"`
+		Leader      bool          `json:"leader"`
+	}
+
+	RaftGroupKind string
+)
+
+// Possible raft group kinds
+const (
+	RaftGroupMeta     RaftGroupKind = "meta"
+	RaftGroupStream   RaftGroupKind = "stream"
+	RaftGroupConsumer RaftGroupKind = "consumer"
+)
+
+// CanRestart checks whether the server with given name can be restarted without any JetStream raft group
+// (meta group, streams and consumers) losing quorum.
+// Groups whose leader is on the server are listed as well, as restarting the server will cause leader election.
+// ErrNoMetaLeader is returned if the JetStream meta leader did not respond.
+func (a *Analyzer) CanRestart(serverName string) (*RestartVerdict, error) {
+	if serverName == "" {
+		return nil, fmt.Errorf("%w: server name cannot be empty", ErrValidation)
+	}
+	jsz, err := a.client.JszPing(JszEventOptions{
+		JszOptions: JszOptions{
+			Accounts:   true,
+			Streams:    true,
+			Consumer:   true,
+			RaftGroups: true,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var found bool
+	for _, resp := range jsz {
+		if resp.Server.Name == serverName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidServerID, serverName)
+	}
+
+	return canRestart(serverName, jsz)
+}
+
+func canRestart(serverName string, jsz []JSZResp) (*RestartVerdict, error) {
+	var clustered bool
+	for _, resp := range jsz {
+		if !resp.JSInfo.Disabled && resp.JSInfo.Meta != nil {
+			clustered = true
+			break
+		}
+	}
+	var meta *MetaClusterInfo
+	if leader := metaLeader(jsz); leader != nil {
+		meta = leader.JSInfo.Meta
+	}
+	if clustered && meta == nil {
+		return nil, ErrNoMetaLeader
+	}
+
+	verdict := &RestartVerdict{
+		Server: serverName,
+		Safe:   true,
+	}
+	addImpact := func(impact *RaftGroupImpact) {
+		if impact == nil {
+			return
+		}
+		if impact.LosesQuorum {
+			verdict.Safe = false
+		}
+		verdict.Groups = append(verdict.Groups, *impact)
+	}
+
+	if meta != nil {
+		peers := make([]*nats.PeerInfo, 0, len(meta.Replicas))
+		for _, peer := range meta.Replicas {
+			if peer != nil {
+				peers = append(peers, &nats.PeerInfo{Name: peer.Name, Current: peer.Current, Offline: peer.Offline, Lag: peer.Lag})
+			}
+		}
+		impact := raftGroupImpact(serverName, &nats.ClusterInfo{Name: meta.Name, Leader: meta.Leader, Replicas: peers})
+		if impact != nil {
+			impact.Kind = RaftGroupMeta
+			impact.RaftGroup = meta.Name
+			// meta group size includes peers which are not known to the leader yet
+			if meta.Size > impact.Size {
+				impact.Size = meta.Size
+				impact.LosesQuorum = impact.Healthy < quorum(impact.Size)
+			}
+			addImpact(impact)
+		}
+	}
+
+	streams := dedupStreams(jsz)
+	keys := make([]streamKey, 0, len(streams))
+	for key := range streams {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].account != keys[j].account {
+			return keys[i].account < keys[j].account
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	consumers := dedupConsumers(jsz)
+	for _, key := range keys {
+		stream := streams[key]
+		if impact := raftGroupImpact(serverName, stream.Cluster); impact != nil {
+			impact.Kind = RaftGroupStream
+			impact.Account = key.account
+			impact.Stream = key.name
+			impact.RaftGroup = stream.RaftGroup
+			addImpact(impact)
+		}
+
+		raftGroups := make(map[string]string, len(stream.ConsumerRaftGroups))
+		for _, group := range stream.ConsumerRaftGroups {
+			if group != nil {
+				raftGroups[group.Name] = group.RaftGroup
+			}
+		}
+		names := make([]string, 0, len(consumers[key]))
+		for name := range consumers[key] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if impact := raftGroupImpact(serverName, consumers[key][name].Cluster); impact != nil {
+				impact.Kind = RaftGroupConsumer
+				impact.Account = key.account
+				impact.Stream = key.name
+				impact.Consumer = name
+				impact.RaftGroup = raftGroups[name]
+				addImpact(impact)
+			}
+		}
+	}
+	return verdict, nil
+}
+
+// raftGroupImpact returns the impact of removing the server from a raft group or nil if the server
+// is not a member of the group or removing it does not affect quorum or leadership.
+func raftGroupImpact(serverName string, cluster *nats.ClusterInfo) *RaftGroupImpact {
+	if cluster == nil {
+		return nil
+	}
+	var member bool
+	impact := &RaftGroupImpact{}
+	if cluster.Leader != "" {
+		impact.Size++
+		impact.Leader = cluster.Leader == serverName
+		member = impact.Leader
+		if !impact.Leader {
+			impact.Healthy++
+		}
+	}
+	for _, peer := range cluster.Replicas {
+		if peer == nil {
+			continue
+		}
+		impact.Size++
+		if peer.Name == serverName {
+			member = true
+			continue
+		}
+		// replicas which are offline or not caught up with the leader cannot be counted towards quorum
+		if peer.Current && !peer.Offline {
+			impact.Healthy++
+		}
+	}
+	if !member {
+		return nil
+	}
+	impact.LosesQuorum = impact.Healthy < quorum(impact.Size)
+	if !impact.LosesQuorum && !impact.Leader {
+		return nil
+	}
+	return impact
+}
+
+func quorum(size int) int {
+	return size/2 + 1
+}
+
+// dedupConsumers collects consumers from all servers, keyed by stream and consumer name.
+// If a consumer is reported by multiple servers, consumer leader's response is used.
+func dedupConsumers(jsz []JSZResp) map[streamKey]map[string]*nats.ConsumerInfo {
+	consumers := make(map[streamKey]map[string]*nats.ConsumerInfo)
+	for _, resp := range jsz {
+		for _, acc := range resp.JSInfo.AccountDetails {
+			if acc == nil {
+				continue
+			}
+			for _, stream := range acc.Streams {
+				key := streamKey{account: acc.Name, name: stream.Name}
+				for _, consumer := range stream.Consumer {
+					if consumer == nil {
+						continue
+					}
+					if _, ok := consumers[key]; !ok {
+						consumers[key] = make(map[string]*nats.ConsumerInfo)
+					}
+					if _, ok := consumers[key][consumer.Name]; ok && (consumer.Cluster == nil || consumer.Cluster.Leader != resp.Server.Name) {
+						continue
+					}
+					consumers[key][consumer.Name] = consumer
+				}
+			}
+		}
+	}
+	return consumers
+}
+
+// metaLeader returns the response of the JetStream meta leader, or nil if the meta leader did not respond.
+func metaLeader(jsz []JSZResp) *JSZResp {
+	for i, resp := range jsz {
+		if resp.JSInfo.Disabled || resp.JSInfo.Meta == nil {
+			continue
+		}
+		if resp.JSInfo.Meta.Leader != "" && resp.JSInfo.Meta.Leader == resp.Server.Name {
+			return &jsz[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package sys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestCanRestart(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	nc, err := nats.Connect(strings.Join(urls, ","))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer nc.Close()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Error getting JetStream context: %v", err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "r3", Subjects: []string{"foo"}, Replicas: 3}); err != nil {
+		t.Fatalf("Error creating stream: %v", err)
+	}
+	r1, err := js.AddStream(&nats.StreamConfig{Name: "r1", Subjects: []string{"bar"}, Replicas: 1})
+	if err != nil {
+		t.Fatalf("Error creating stream: %v", err)
+	}
+	r1Server := r1.Cluster.Leader
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	// wait for all replicas to report in
+	time.Sleep(500 * time.Millisecond)
+
+	for _, srv := range c.servers {
+		verdict, err := sys.CanRestart(srv.Name())
+		if err != nil {
+			t.Fatalf("Unable to check restart readiness: %s", err)
+		}
+		if srv.Name() != r1Server {
+			if !verdict.Safe {
+				t.Fatalf("Expected server %q to be safe to restart: %+v", srv.Name(), verdict)
+			}
+			for _, group := range verdict.Groups {
+				if !group.Leader {
+					t.Fatalf("Expected only groups with leader on server %q: %+v", srv.Name(), group)
+				}
+			}
+			continue
+		}
+		if verdict.Safe {
+			t.Fatalf("Expected server %q not to be safe to restart: %+v", srv.Name(), verdict)
+		}
+		var found bool
+		for _, group := range verdict.Groups {
+			if group.Kind == RaftGroupStream && group.Stream == "r1" && group.LosesQuorum {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("Expected stream %q to lose quorum: %+v", "r1", verdict)
+		}
+	}
+
+	if _, err := sys.CanRestart(""); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected error; want: %s; got: %s", ErrValidation, err)
+	}
+	if _, err := sys.CanRestart("asd"); !errors.Is(err, ErrInvalidServerID) {
+		t.Fatalf("Expected error; want: %s; got: %s", ErrInvalidServerID, err)
+	}
+}
+
+func TestCanRestartRaftGroups(t *testing.T) {
+	jsz := []JSZResp{
+		{
+			Server: ServerInfo{Name: "s1"},
+			JSInfo: JSInfo{
+				Meta: &MetaClusterInfo{Name: "C1", Leader: "s1", Size: 3, Replicas: []*PeerInfo{
+					{Name: "s2", Current: true},
+					{Name: "s3", Current: false, Lag: 10},
+				}},
+				AccountDetails: []*AccountDetail{
+					{
+						Name: "JS",
+						Streams: []StreamDetail{
+							{
+								Name:      "s1",
+								RaftGroup: "S-R3F-abc",
+								Cluster: &nats.ClusterInfo{Leader: "s2", Replicas: []*nats.PeerInfo{
+									{Name: "s1", Current: true},
+									{Name: "s3", Current: true},
+								}},
+								Consumer: []*nats.ConsumerInfo{
+									{Name: "c1", Cluster: &nats.ClusterInfo{Leader: "s3", Replicas: []*nats.PeerInfo{
+										{Name: "s1", Current: true},
+										{Name: "s2", Current: true, Offline: true},
+									}}},
+								},
+								ConsumerRaftGroups: []*RaftGroupDetail{{Name: "c1", RaftGroup: "C-R3F-def"}},
+							},
+							{
+								Name:      "s2",
+								RaftGroup: "S-R3F-ghi",
+								Cluster: &nats.ClusterInfo{Leader: "s1", Replicas: []*nats.PeerInfo{
+									{Name: "s2", Current: false, Lag: 5},
+									{Name: "s3", Current: true},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		server   string
+		safe     bool
+		expected []RaftGroupImpact
+	}{
+		{
+			name:   "meta leader with lagging meta peer",
+			server: "s1",
+			safe:   false,
+			expected: []RaftGroupImpact{
+				{Kind: RaftGroupMeta, RaftGroup: "C1", Size: 3, Healthy: 1, LosesQuorum: true, Leader: true},
+				{Kind: RaftGroupConsumer, Account: "JS", Stream: "s1", Consumer: "c1", RaftGroup: "C-R3F-def", Size: 3, Healthy: 1, LosesQuorum: true},
+				{Kind: RaftGroupStream, Account: "JS", Stream: "s2", RaftGroup: "S-R3F-ghi", Size: 3, Healthy: 1, LosesQuorum: true, Leader: true},
+			},
+		},
+		{
+			name:   "stream leader",
+			server: "s2",
+			safe:   false,
+			expected: []RaftGroupImpact{
+				{Kind: RaftGroupMeta, RaftGroup: "C1", Size: 3, Healthy: 1, LosesQuorum: true},
+				{Kind: RaftGroupStream, Account: "JS", Stream: "s1", RaftGroup: "S-R3F-abc", Size: 3, Healthy: 2, Leader: true},
+			},
+		},
+		{
+			name:   "replica with lagging peer",
+			server: "s3",
+			safe:   false,
+			expected: []RaftGroupImpact{
+				{Kind: RaftGroupConsumer, Account: "JS", Stream: "s1", Consumer: "c1", RaftGroup: "C-R3F-def", Size: 3, Healthy: 1, LosesQuorum: true, Leader: true},
+				{Kind: RaftGroupStream, Account: "JS", Stream: "s2", RaftGroup: "S-R3F-ghi", Size: 3, Healthy: 1, LosesQuorum: true},
+			},
+		},
+		{
+			name:   "not a member of any group",
+			server: "s4",
+			safe:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verdict, err := canRestart(test.server, jsz)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if verdict.Safe != test.safe {
+				t.Fatalf("Invalid verdict; want safe: %t; got: %+v", test.safe, verdict)
+			}
+			if len(verdict.Groups) != len(test.expected) {
+				t.Fatalf("Invalid number of groups; want: %d; got: %+v", len(test.expected), verdict.Groups)
+			}
+			for i, group := range test.expected {
+				if verdict.Groups[i] != group {
+					t.Fatalf("Invalid group impact; want: %+v; got: %+v", group, verdict.Groups[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCanRestartWithoutMetaLeader(t *testing.T) {
+	// meta group replicas are only reported by the meta leader
+	jsz := []JSZResp{
+		{
+			Server: ServerInfo{Name: "s2"},
+			JSInfo: JSInfo{Meta: &MetaClusterInfo{Name: "C1", Leader: "s1", Size: 3}},
+		},
+		{
+			Server: ServerInfo{Name: "s3"},
+			JSInfo: JSInfo{Meta: &MetaClusterInfo{Name: "C1", Leader: "s1", Size: 3}},
+		},
+	}
+	if _, err := canRestart("s2", jsz); !errors.Is(err, ErrNoMetaLeader) {
+		t.Fatalf("Expected error: %s; got: %v", ErrNoMetaLeader, err)
+	}
+
+	// without clustered JetStream there is no meta group to check
+	verdict, err := canRestart("s1", []JSZResp{{Server: ServerInfo{Name: "s1"}, JSInfo: JSInfo{Disabled: true}}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !verdict.Safe {
+		t.Fatalf("Expected restart to be safe: %+v", verdict)
+	}
+}
@@ -0,0 +1,335 @@
+package sys
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const DefaultCapacityTopStreams = 10
+
+type (
+	// JetStreamCapacityReport describes JetStream memory and file storage usage
+	// per server, cluster and domain, along with account and stream usage.
+	JetStreamCapacityReport struct {
+		Time       time.Time         `json:"time"`
+		Servers    []ServerCapacity  `json:"servers"`
+		Clusters   []GroupCapacity   `json:"clusters"`
+		Domains    []GroupCapacity   `json:"domains"`
+		Accounts   []AccountCapacity `json:"accounts"`
+		TopStreams []StreamCapacity  `json:"top_streams"`
+		Total      StorageCapacities `json:"total"`
+	}
+
+	// StorageCapacities contains usage of both JetStream storage types.
+	StorageCapacities struct {
+		Memory StorageCapacity `json:"memory"`
+		Store  StorageCapacity `json:"storage"`
+	}
+
+	// StorageCapacity describes usage of a single storage type, in bytes.
+	//
+	// Free is the limit minus used or reserved storage, whichever is greater.
+	// Growth (bytes per second) and TimeToFull are only set for projected reports, see Project.
+	// TimeToFull is not set if usage does not grow or storage is not limited.
+	StorageCapacity struct {
+		Limit      uint64         `json:"limit"`
+		Used       uint64         `json:"used"`
+		Reserved   uint64         `json:"reserved"`
+		Free       uint64         `json:"free"`
+		Growth     float64        `json:"growth,omitempty"`
+		TimeToFull *time.Duration `json:"time_to_full,omitempty"`
+	}
+
+	// ServerCapacity is JetStream storage usage of a single server.
+	ServerCapacity struct {
+		Server  ServerInfo `json:"server"`
+		Cluster string     `json:"cluster,omitempty"`
+		Domain  string     `json:"domain,omitempty"`
+		StorageCapacities
+	}
+
+	// GroupCapacity is JetStream storage usage summed over servers in a cluster or domain.
+	GroupCapacity struct {
+		Name    string `json:"name"`
+		Servers int    `json:"servers"`
+		StorageCapacities
+	}
+
+	// AccountCapacity is JetStream storage used by an account, along with its share
+	// of storage used by all accounts (between 0 and 1).
+	AccountCapacity struct {
+		Name        string  `json:"name"`
+		Memory      uint64  `json:"memory"`
+		Store       uint64  `json:"storage"`
+		MemoryShare float64 `json:"memory_share"`
+		StoreShare  float64 `json:"storage_share"`
+	}
+
+	// StreamCapacity is the size of a single stream replica.
+	StreamCapacity struct {
+		Account  string `json:"account"`
+		Name     string `json:"name"`
+		Storage  string `json:"storage,omitempty"`
+		Replicas int    `json:"replicas,omitempty"`
+		Messages uint64 `json:"messages"`
+		Bytes    uint64 `json:"bytes"`
+	}
+
+	// JetStreamCapacityOptions are options passed to JetStreamCapacity
+	JetStreamCapacityOptions struct {
+		// TopStreams is the number of largest streams included in the report, DefaultCapacityTopStreams by default.
+		TopStreams int
+
+		// SampleInterval, if set, is the time between two samples used to project time to full storage.
+		// Projections over longer periods can be made by calling Project with a previous report.
+		SampleInterval time.Duration
+	}
+)
+
+// JetStreamCapacity reports JetStream storage usage and limits of all servers, grouped by cluster and domain.
+// Account usage is account-wide, as reported by servers, and stream sizes are taken from stream leaders.
+func (a *Analyzer) JetStreamCapacity(opts JetStreamCapacityOptions) (*JetStreamCapacityReport, error) {
+	if opts.TopStreams < 0 {
+		return nil, fmt.Errorf("%w: number of top streams cannot be negative", ErrValidation)
+	}
+	if opts.SampleInterval < 0 {
+		return nil, fmt.Errorf("%w: sample interval cannot be negative", ErrValidation)
+	}
+	jszOpts := JszEventOptions{
+		JszOptions: JszOptions{
+			Accounts: true,
+			Streams:  true,
+			Config:   true,
+		},
+	}
+	jsz, err := a.client.JszPing(jszOpts)
+	if err != nil {
+		return nil, err
+	}
+	report := jetStreamCapacity(opts, time.Now().UTC(), jsz)
+	if opts.SampleInterval == 0 {
+		return report, nil
+	}
+
+	time.Sleep(opts.SampleInterval)
+	if jsz, err = a.client.JszPing(jszOpts); err != nil {
+		return nil, err
+	}
+	latest := jetStreamCapacity(opts, time.Now().UTC(), jsz)
+	latest.Project(report)
+	return latest, nil
+}
+
+func jetStreamCapacity(opts JetStreamCapacityOptions, now time.Time, jsz []JSZResp) *JetStreamCapacityReport {
+	topStreams := opts.TopStreams
+	if topStreams == 0 {
+		topStreams = DefaultCapacityTopStreams
+	}
+	report := &JetStreamCapacityReport{
+		Time:       now,
+		Servers:    make([]ServerCapacity, 0, len(jsz)),
+		Clusters:   make([]GroupCapacity, 0),
+		Domains:    make([]GroupCapacity, 0),
+		Accounts:   make([]AccountCapacity, 0),
+		TopStreams: make([]StreamCapacity, 0),
+	}
+
+	clusters := make(map[string]*GroupCapacity)
+	domains := make(map[string]*GroupCapacity)
+	accounts := make(map[string]*AccountCapacity)
+	for _, resp := range jsz {
+		info := resp.JSInfo
+		if info.Disabled {
+			continue
+		}
+		server := ServerCapacity{
+			Server:  resp.Server,
+			Cluster: resp.Server.Cluster,
+			Domain:  info.Config.Domain,
+			StorageCapacities: StorageCapacities{
+				Memory: newStorageCapacity(info.Config.MaxMemory, info.Memory, info.ReservedMemory),
+				Store:  newStorageCapacity(info.Config.MaxStore, info.Store, info.ReservedStore),
+			},
+		}
+		report.Servers = append(report.Servers, server)
+		report.Total.add(server.StorageCapacities)
+		addGroupCapacity(clusters, server.Cluster, server.StorageCapacities)
+		addGroupCapacity(domains, server.Domain, server.StorageCapacities)
+
+		for _, acc := range info.AccountDetails {
+			if acc == nil {
+				continue
+			}
+			account, ok := accounts[acc.Name]
+			if !ok {
+				account = &AccountCapacity{Name: acc.Name}
+				accounts[acc.Name] = account
+			}
+			// account usage is reported by each server the account is used on
+			if acc.Memory > account.Memory {
+				account.Memory = acc.Memory
+			}
+			if acc.Store > account.Store {
+				account.Store = acc.Store
+			}
+		}
+	}
+	sort.Slice(report.Servers, func(i, j int) bool {
+		return serverName(report.Servers[i].Server) < serverName(report.Servers[j].Server)
+	})
+	report.Clusters = sortedGroupCapacity(clusters)
+	report.Domains = sortedGroupCapacity(domains)
+
+	var memory, store uint64
+	for _, account := range accounts {
+		memory += account.Memory
+		store += account.Store
+	}
+	for _, account := range accounts {
+		if memory > 0 {
+			account.MemoryShare = float64(account.Memory) / float64(memory)
+		}
+		if store > 0 {
+			account.StoreShare = float64(account.Store) / float64(store)
+		}
+		report.Accounts = append(report.Accounts, *account)
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool {
+		return report.Accounts[i].Name < report.Accounts[j].Name
+	})
+
+	for key, stream := range dedupStreams(jsz) {
+		sc := StreamCapacity{
+			Account:  key.account,
+			Name:     key.name,
+			Messages: stream.State.Msgs,
+			Bytes:    stream.State.Bytes,
+		}
+		if stream.Config != nil {
+			sc.Storage = stream.Config.Storage.String()
+			sc.Replicas = stream.Config.Replicas
+		}
+		report.TopStreams = append(report.TopStreams, sc)
+	}
+	sort.Slice(report.TopStreams, func(i, j int) bool {
+		if report.TopStreams[i].Bytes != report.TopStreams[j].Bytes {
+			return report.TopStreams[i].Bytes > report.TopStreams[j].Bytes
+		}
+		if report.TopStreams[i].Account != report.TopStreams[j].Account {
+			return report.TopStreams[i].Account < report.TopStreams[j].Account
+		}
+		return report.TopStreams[i].Name < report.TopStreams[j].Name
+	})
+	if len(report.TopStreams) > topStreams {
+		report.TopStreams = report.TopStreams[:topStreams]
+	}
+	return report
+}
+
+func newStorageCapacity(limit int64, used, reserved uint64) StorageCapacity {
+	c := StorageCapacity{Used: used, Reserved: reserved}
+	if limit > 0 {
+		c.Limit = uint64(limit)
+	}
+	c.updateFree()
+	return c
+}
+
+func (c *StorageCapacity) updateFree() {
+	taken := c.Used
+	if c.Reserved > taken {
+		taken = c.Reserved
+	}
+	c.Free = 0
+	if c.Limit > taken {
+		c.Free = c.Limit - taken
+	}
+}
+
+func (c *StorageCapacity) add(other StorageCapacity) {
+	c.Limit += other.Limit
+	c.Used += other.Used
+	c.Reserved += other.Reserved
+	c.updateFree()
+}
+
+func (m *StorageCapacities) add(other StorageCapacities) {
+	m.Memory.add(other.Memory)
+	m.Store.add(other.Store)
+}
+
+func addGroupCapacity(groups map[string]*GroupCapacity, name string, capacity StorageCapacities) {
+	group, ok := groups[name]
+	if !ok {
+		group = &GroupCapacity{Name: name}
+		groups[name] = group
+	}
+	group.Servers++
+	group.add(capacity)
+}
+
+func sortedGroupCapacity(groups map[string]*GroupCapacity) []GroupCapacity {
+	res := make([]GroupCapacity, 0, len(groups))
+	for _, group := range groups {
+		res = append(res, *group)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// Project sets storage growth and time to full storage, based on usage reported in a previous report.
+// Servers, clusters and domains missing from the previous report are not projected.
+func (r *JetStreamCapacityReport) Project(previous *JetStreamCapacityReport) {
+	elapsed := r.Time.Sub(previous.Time).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	servers := make(map[string]StorageCapacities, len(previous.Servers))
+	for _, server := range previous.Servers {
+		servers[server.Server.ID] = server.StorageCapacities
+	}
+	for i := range r.Servers {
+		if prev, ok := servers[r.Servers[i].Server.ID]; ok {
+			r.Servers[i].project(prev, elapsed)
+		}
+	}
+	projectGroups(r.Clusters, previous.Clusters, elapsed)
+	projectGroups(r.Domains, previous.Domains, elapsed)
+	r.Total.project(previous.Total, elapsed)
+}
+
+func projectGroups(groups, previous []GroupCapacity, elapsed float64) {
+	prev := make(map[string]StorageCapacities, len(previous))
+	for _, group := range previous {
+		prev[group.Name] = group.StorageCapacities
+	}
+	for i := range groups {
+		if p, ok := prev[groups[i].Name]; ok {
+			groups[i].project(p, elapsed)
+		}
+	}
+}
+
+func (m *StorageCapacities) project(previous StorageCapacities, elapsed float64) {
+	m.Memory.project(previous.Memory, elapsed)
+	m.Store.project(previous.Store, elapsed)
+}
+
+func (c *StorageCapacity) project(previous StorageCapacity, elapsed float64) {
+	c.Growth = (float64(c.Used) - float64(previous.Used)) / elapsed
+	c.TimeToFull = nil
+	if c.Growth <= 0 || c.Limit == 0 {
+		return
+	}
+	seconds := float64(c.Free) / c.Growth
+	if seconds > math.MaxInt64/float64(time.Second) {
+		return
+	}
+	ttf := time.Duration(seconds * float64(time.Second))
+	c.TimeToFull = &ttf
+}
@@ -0,0 +1,143 @@
+package sys
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestJetStreamCapacity(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	nc, err := nats.Connect(strings.Join(urls, ","))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Error getting JetStream context: %v", err)
+	}
+	for _, name := range []string{"small", "large"} {
+		if _, err := js.AddStream(&nats.StreamConfig{Name: name, Subjects: []string{name}, Replicas: 3}); err != nil {
+			t.Fatalf("Error creating stream: %v", err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := js.Publish("large", []byte(strings.Repeat("x", 1024))); err != nil {
+			t.Fatalf("Error publishing message: %v", err)
+		}
+	}
+	if _, err := js.Publish("small", []byte("x")); err != nil {
+		t.Fatalf("Error publishing message: %v", err)
+	}
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	if _, err := sys.JetStreamCapacity(JetStreamCapacityOptions{TopStreams: -1}); err == nil {
+		t.Fatalf("Expected validation error")
+	}
+
+	report, err := sys.JetStreamCapacity(JetStreamCapacityOptions{TopStreams: 1})
+	if err != nil {
+		t.Fatalf("Unable to fetch JetStream capacity: %s", err)
+	}
+	if len(report.Servers) != 3 {
+		t.Fatalf("Invalid number of servers: %d; want: %d", len(report.Servers), 3)
+	}
+	if len(report.Clusters) != 1 || report.Clusters[0].Name != "C1" || report.Clusters[0].Servers != 3 {
+		t.Fatalf("Invalid clusters: %+v", report.Clusters)
+	}
+	if report.Total.Store.Limit == 0 || report.Total.Store.Free > report.Total.Store.Limit {
+		t.Fatalf("Invalid total storage: %+v", report.Total.Store)
+	}
+	if len(report.TopStreams) != 1 || report.TopStreams[0].Name != "large" || report.TopStreams[0].Messages != 10 {
+		t.Fatalf("Invalid top streams: %+v", report.TopStreams)
+	}
+	if len(report.Accounts) != 1 || report.Accounts[0].Name != "JS" || report.Accounts[0].StoreShare != 1 {
+		t.Fatalf("Invalid accounts: %+v", report.Accounts)
+	}
+}
+
+func TestJetStreamCapacityProject(t *testing.T) {
+	jszResp := func(name, cluster string, used uint64) JSZResp {
+		return JSZResp{
+			Server: ServerInfo{ID: strings.ToUpper(name), Name: name, Cluster: cluster},
+			JSInfo: JSInfo{
+				Config:         JetStreamConfig{MaxStore: 1000, MaxMemory: 100, Domain: "hub"},
+				JetStreamStats: JetStreamStats{Store: used, ReservedStore: 200},
+				AccountDetails: []*AccountDetail{
+					{Name: "A", JetStreamStats: JetStreamStats{Store: used / 4}},
+					{Name: "B", JetStreamStats: JetStreamStats{Store: used / 4 * 3}},
+				},
+			},
+		}
+	}
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	previous := jetStreamCapacity(JetStreamCapacityOptions{}, start, []JSZResp{
+		jszResp("s1", "C1", 100),
+		jszResp("s2", "C1", 400),
+		jszResp("s3", "C2", 500),
+	})
+	report := jetStreamCapacity(JetStreamCapacityOptions{}, start.Add(100*time.Second), []JSZResp{
+		jszResp("s1", "C1", 100),
+		jszResp("s2", "C1", 600),
+		jszResp("s3", "C2", 500),
+	})
+	report.Project(previous)
+
+	s1, s2 := report.Servers[0].Store, report.Servers[1].Store
+	if s1.Free != 800 || s1.Growth != 0 || s1.TimeToFull != nil {
+		t.Fatalf("Invalid s1 storage: %+v", s1)
+	}
+	if s2.Free != 400 || s2.Growth != 2 || s2.TimeToFull == nil || *s2.TimeToFull != 200*time.Second {
+		t.Fatalf("Invalid s2 storage: %+v", s2)
+	}
+	if report.Servers[0].Memory.Free != 100 {
+		t.Fatalf("Invalid s1 memory: %+v", report.Servers[0].Memory)
+	}
+
+	if len(report.Clusters) != 2 || report.Clusters[0].Name != "C1" || report.Clusters[0].Servers != 2 {
+		t.Fatalf("Invalid clusters: %+v", report.Clusters)
+	}
+	c1 := report.Clusters[0].Store
+	if c1.Limit != 2000 || c1.Used != 700 || c1.Reserved != 400 || c1.Free != 1300 || *c1.TimeToFull != 650*time.Second {
+		t.Fatalf("Invalid C1 storage: %+v", c1)
+	}
+	if len(report.Domains) != 1 || report.Domains[0].Name != "hub" || report.Domains[0].Servers != 3 || report.Domains[0].Store.Used != 1200 {
+		t.Fatalf("Invalid domains: %+v", report.Domains)
+	}
+	if report.Total.Store.Used != 1200 || report.Total.Store.Growth != 2 {
+		t.Fatalf("Invalid total storage: %+v", report.Total.Store)
+	}
+
+	// account usage is account-wide, so the highest reported value is used
+	expected := []AccountCapacity{
+		{Name: "A", Store: 150, StoreShare: 0.25},
+		{Name: "B", Store: 450, StoreShare: 0.75},
+	}
+	if len(report.Accounts) != len(expected) {
+		t.Fatalf("Invalid accounts: %+v", report.Accounts)
+	}
+	for i, account := range report.Accounts {
+		if account != expected[i] {
+			t.Fatalf("Invalid account; want: %+v; got: %+v", expected[i], account)
+		}
+	}
+}
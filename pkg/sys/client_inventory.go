@@ -0,0 +1,276 @@
+package sys
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultCertExpiryWarning = 30 * 24 * time.Hour
+
+type (
+	// ClientInventory lists open client connections from all servers,
+	// aggregated by client library, TLS version and cipher suite.
+	ClientInventory struct {
+		Total        int              `json:"total"`
+		ByClient     []InventoryCount `json:"by_client,omitempty"`
+		ByTLSVersion []InventoryCount `json:"by_tls_version,omitempty"`
+		ByTLSCipher  []InventoryCount `json:"by_tls_cipher,omitempty"`
+		ByFlag       []InventoryCount `json:"by_flag,omitempty"`
+		Clients      []ClientRecord   `json:"clients,omitempty"`
+	}
+
+	// InventoryCount is the number of connections sharing the same key.
+	InventoryCount struct {
+		Key   string `json:"key"`
+		Count int    `json:"count"`
+	}
+
+	// ClientRecord describes a single client connection.
+	ClientRecord struct {
+		Server     string       `json:"server"`
+		ServerID   string       `json:"server_id"`
+		Cid        uint64       `json:"cid"`
+		Name       string       `json:"name,omitempty"`
+		IP         string       `json:"ip"`
+		Account    string       `json:"account,omitempty"`
+		User       string       `json:"user,omitempty"`
+		Lang       string       `json:"lang,omitempty"`
+		Version    string       `json:"version,omitempty"`
+		TLSVersion string       `json:"tls_version,omitempty"`
+		TLSCipher  string       `json:"tls_cipher_suite,omitempty"`
+		Flags      []ClientFlag `json:"flags,omitempty"`
+	}
+
+	ClientFlag string
+
+	// ClientInventoryOptions are options passed to ClientInventory
+	ClientInventoryOptions struct {
+		// MinVersions maps client library language (as reported in CONNZ, e.g. "go") to the minimum
+		// library version which is not considered outdated.
+		MinVersions map[string]string
+
+		// CertExpiry returns expiration time of a peer certificate.
+		// Servers do not report certificate expiration, so it has to be looked up by the caller,
+		// e.g. by certificate fingerprint. If not set, certificates are not checked.
+		CertExpiry func(TLSPeerCert) (time.Time, bool)
+
+		// CertExpiryWarning is the time before certificate expiration at which the connection is flagged,
+		// DefaultCertExpiryWarning by default.
+		CertExpiryWarning time.Duration
+	}
+)
+
+// Possible client flags
+const (
+	FlagOutdatedVersion ClientFlag = "outdated_version" // Client library is older than configured minimum version
+	FlagNoTLS           ClientFlag = "no_tls"           // Connection does not use TLS
+	FlagCertExpiring    ClientFlag = "cert_expiring"    // Peer certificate expires soon or is already expired
+)
+
+// noTLS is used as TLS version and cipher key for connections without TLS
+const noTLS = "none"
+
+// ClientInventory aggregates all open client connections in the cluster
+// and flags connections with outdated client libraries, without TLS or with expiring certificates.
+func (a *Analyzer) ClientInventory(opts ClientInventoryOptions) (*ClientInventory, error) {
+	connz, err := connzAll(a.client, ConnzEventOptions{
+		ConnzOptions: ConnzOptions{
+			Username: true,
+			State:    ConnOpen,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clientInventory(opts, connz, time.Now()), nil
+}
+
+func clientInventory(opts ClientInventoryOptions, connz []ConnzResp, now time.Time) *ClientInventory {
+	warning := opts.CertExpiryWarning
+	if warning <= 0 {
+		warning = DefaultCertExpiryWarning
+	}
+
+	inv := &ClientInventory{}
+	byClient := make(map[string]int)
+	byTLSVersion := make(map[string]int)
+	byTLSCipher := make(map[string]int)
+	byFlag := make(map[string]int)
+
+	for _, resp := range connz {
+		for _, conn := range resp.Connz.Conns {
+			if conn == nil {
+				continue
+			}
+			record := ClientRecord{
+				Server:     serverName(resp.Server),
+				ServerID:   resp.Server.ID,
+				Cid:        conn.Cid,
+				Name:       conn.Name,
+				IP:         conn.IP,
+				Account:    conn.Account,
+				User:       conn.AuthorizedUser,
+				Lang:       conn.Lang,
+				Version:    conn.Version,
+				TLSVersion: conn.TLSVersion,
+				TLSCipher:  conn.TLSCipher,
+			}
+
+			if minVersion, ok := opts.MinVersions[conn.Lang]; ok && compareVersions(conn.Version, minVersion) < 0 {
+				record.Flags = append(record.Flags, FlagOutdatedVersion)
+			}
+			if conn.TLSVersion == "" {
+				record.Flags = append(record.Flags, FlagNoTLS)
+			}
+			if opts.CertExpiry != nil {
+				for _, cert := range conn.TLSPeerCerts {
+					if cert == nil {
+						continue
+					}
+					if expiry, ok := opts.CertExpiry(*cert); ok && expiry.Sub(now) < warning {
+						record.Flags = append(record.Flags, FlagCertExpiring)
+						break
+					}
+				}
+			}
+
+			inv.Total++
+			byClient[strings.TrimSpace(conn.Lang+" "+conn.Version)]++
+			byTLSVersion[valueOr(conn.TLSVersion, noTLS)]++
+			byTLSCipher[valueOr(conn.TLSCipher, noTLS)]++
+			for _, flag := range record.Flags {
+				byFlag[string(flag)]++
+			}
+			inv.Clients = append(inv.Clients, record)
+		}
+	}
+
+	inv.ByClient = sortedInventoryCounts(byClient)
+	inv.ByTLSVersion = sortedInventoryCounts(byTLSVersion)
+	inv.ByTLSCipher = sortedInventoryCounts(byTLSCipher)
+	inv.ByFlag = sortedInventoryCounts(byFlag)
+	sort.SliceStable(inv.Clients, func(i, j int) bool {
+		if inv.Clients[i].Server != inv.Clients[j].Server {
+			return inv.Clients[i].Server < inv.Clients[j].Server
+		}
+		return inv.Clients[i].Cid < inv.Clients[j].Cid
+	})
+	return inv
+}
+
+// WriteJSON writes the inventory as JSON.
+func (inv *ClientInventory) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(inv)
+}
+
+// WriteCSV writes a CSV row for each client connection, preceded by a header.
+// Connection flags are separated with "|".
+func (inv *ClientInventory) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"server", "server_id", "cid", "name", "ip", "account", "user", "lang", "version", "tls_version", "tls_cipher_suite", "flags"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, client := range inv.Clients {
+		flags := make([]string, 0, len(client.Flags))
+		for _, flag := range client.Flags {
+			flags = append(flags, string(flag))
+		}
+		row := []string{
+			client.Server,
+			client.ServerID,
+			strconv.FormatUint(client.Cid, 10),
+			client.Name,
+			client.IP,
+			client.Account,
+			client.User,
+			client.Lang,
+			client.Version,
+			client.TLSVersion,
+			client.TLSCipher,
+			strings.Join(flags, "|"),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func sortedInventoryCounts(counts map[string]int) []InventoryCount {
+	res := make([]InventoryCount, 0, len(counts))
+	for key, count := range counts {
+		res = append(res, InventoryCount{Key: key, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+func valueOr(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// compareVersions compares dotted numeric versions, ignoring "v" prefix and pre-release suffixes.
+// It returns -1, 0 or 1. Versions which cannot be parsed are considered equal.
+func compareVersions(a, b string) int {
+	av, ok := parseVersion(a)
+	if !ok {
+		return 0
+	}
+	bv, ok := parseVersion(b)
+	if !ok {
+		return 0
+	}
+	for i := 0; i < len(av) || i < len(bv); i++ {
+		var x, y int
+		if i < len(av) {
+			x = av[i]
+		}
+		if i < len(bv) {
+			y = bv[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if idx := strings.IndexAny(version, "-+ "); idx >= 0 {
+		version = version[:idx]
+	}
+	if version == "" {
+		return nil, false
+	}
+	parts := strings.Split(version, ".")
+	res := make([]int, 0, len(parts))
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		res = append(res, n)
+	}
+	return res, true
+}
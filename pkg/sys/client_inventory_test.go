@@ -0,0 +1,153 @@
+package sys
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestClientInventory(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	for _, s := range c.servers {
+		nc, err := nats.Connect(s.ClientURL(), nats.Name("inventory"))
+		if err != nil {
+			t.Fatalf("Error establishing connection: %s", err)
+		}
+		defer nc.Close()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	inv, err := sys.ClientInventory(ClientInventoryOptions{
+		MinVersions: map[string]string{"go": "999.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("Unable to fetch client inventory: %s", err)
+	}
+	// includes the system connection
+	if inv.Total != 4 {
+		t.Fatalf("Invalid number of connections: %d; want: %d", inv.Total, 4)
+	}
+	if len(inv.ByClient) != 1 || inv.ByClient[0].Key != "go "+nats.Version || inv.ByClient[0].Count != 4 {
+		t.Fatalf("Invalid client versions: %+v", inv.ByClient)
+	}
+	if len(inv.ByTLSVersion) != 1 || inv.ByTLSVersion[0].Key != "none" {
+		t.Fatalf("Invalid TLS versions: %+v", inv.ByTLSVersion)
+	}
+	flags := make(map[string]int)
+	for _, flag := range inv.ByFlag {
+		flags[flag.Key] = flag.Count
+	}
+	if flags[string(FlagOutdatedVersion)] != 4 || flags[string(FlagNoTLS)] != 4 {
+		t.Fatalf("Invalid flags: %+v", inv.ByFlag)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := inv.WriteCSV(buf); err != nil {
+		t.Fatalf("Unable to write CSV: %s", err)
+	}
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Unable to read CSV: %s", err)
+	}
+	if len(rows) != 5 || rows[0][0] != "server" || rows[1][11] != "outdated_version|no_tls" {
+		t.Fatalf("Invalid CSV output: %v", rows)
+	}
+
+	buf.Reset()
+	if err := inv.WriteJSON(buf); err != nil {
+		t.Fatalf("Unable to write JSON: %s", err)
+	}
+	var decoded ClientInventory
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Unable to read JSON: %s", err)
+	}
+	if decoded.Total != inv.Total || len(decoded.Clients) != len(inv.Clients) {
+		t.Fatalf("Invalid JSON output: %+v", decoded)
+	}
+}
+
+func TestClientInventoryCertExpiry(t *testing.T) {
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiry := map[string]time.Time{
+		"expiring": now.Add(24 * time.Hour),
+		"valid":    now.Add(365 * 24 * time.Hour),
+	}
+	connz := []ConnzResp{
+		{Connz: Connz{Conns: []*ConnInfo{
+			{Cid: 1, Lang: "go", Version: "1.24.0", TLSVersion: "1.3", TLSCipher: "TLS_AES_128_GCM_SHA256", TLSPeerCerts: []*TLSPeerCert{{CertSha256: "expiring"}}},
+			{Cid: 2, Lang: "go", Version: "v1.25.0-beta", TLSVersion: "1.3", TLSCipher: "TLS_AES_128_GCM_SHA256", TLSPeerCerts: []*TLSPeerCert{{CertSha256: "valid"}}},
+			{Cid: 3, Lang: "python3", Version: "2.2.0"},
+		}}},
+	}
+	inv := clientInventory(ClientInventoryOptions{
+		MinVersions: map[string]string{"go": "1.25.0"},
+		CertExpiry: func(cert TLSPeerCert) (time.Time, bool) {
+			t, ok := expiry[cert.CertSha256]
+			return t, ok
+		},
+	}, connz, now)
+
+	expected := [][]ClientFlag{
+		{FlagOutdatedVersion, FlagCertExpiring},
+		nil,
+		{FlagNoTLS},
+	}
+	for i, flags := range expected {
+		if len(inv.Clients[i].Flags) != len(flags) {
+			t.Fatalf("Invalid flags for client %d; want: %v; got: %v", i, flags, inv.Clients[i].Flags)
+		}
+		for j, flag := range flags {
+			if inv.Clients[i].Flags[j] != flag {
+				t.Fatalf("Invalid flags for client %d; want: %v; got: %v", i, flags, inv.Clients[i].Flags)
+			}
+		}
+	}
+	if inv.ByTLSCipher[0].Key != "TLS_AES_128_GCM_SHA256" || inv.ByTLSCipher[0].Count != 2 {
+		t.Fatalf("Invalid TLS ciphers: %+v", inv.ByTLSCipher)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.24.0", "1.24.0", 0},
+		{"1.9.0", "1.24.0", -1},
+		{"v2.0", "1.99.99", 1},
+		{"1.2", "1.2.0", 0},
+		{"1.2.0-beta.1", "1.2.0", 0},
+		{"unknown", "1.0.0", 0},
+	}
+	for _, test := range tests {
+		if res := compareVersions(test.a, test.b); res != test.expected {
+			t.Fatalf("Invalid comparison of %q and %q; want: %d; got: %d", test.a, test.b, test.expected, res)
+		}
+	}
+}
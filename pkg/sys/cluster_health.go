@@ -0,0 +1,288 @@
+package sys
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// ClusterHealthReport is a cluster-wide health verdict built from HEALTHZ, VARZ, STATSZ and JSZ responses.
+	ClusterHealthReport struct {
+		Status            HealthStatus    `json:"status"`
+		ServersExpected   int             `json:"servers_expected"`
+		ServersResponding int             `json:"servers_responding"`
+		MetaLeader        string          `json:"meta_leader,omitempty"`
+		Findings          []HealthFinding `json:"findings,omitempty"`
+	}
+
+	// HealthFinding describes a single problem detected in the cluster.
+	HealthFinding struct {
+		Kind     HealthFindingKind `json:"kind"`
+		Status   HealthStatus      `json:"status"`
+		Server   string            `json:"server,omitempty"`
+		ServerID string            `json:"server_id,omitempty"`
+		Consumer string            `json:"consumer,omitempty"`
+		Message  string            `json:"message"`
+	}
+
+	HealthFindingKind string
+
+	// ClusterHealthOptions are options passed to ClusterHealth
+	ClusterHealthOptions struct {
+		// ExpectedServers is the number of servers which should respond.
+		// If not set, the number reported by the client is used if it implements ServerCountProvider
+		// (e.g. System with ServerCount option) and, if that is not known either,
+		// the size of JetStream meta group or the number of responding servers, whichever is greater.
+		ExpectedServers int
+
+		// MaxReplicaLag is the number of operations a meta group replica can lag behind the leader
+		// before being reported. If not set, only replicas the server reports as not current are reported,
+		// as a current replica may lag by a few operations on a cluster taking writes.
+		MaxReplicaLag uint64
+
+		// Healthz are the options passed to HEALTHZ requests.
+		Healthz HealthzOptions
+	}
+)
+
+// Possible health finding kinds
+const (
+	FindingServersMissing  HealthFindingKind = "servers_missing"  // Fewer servers responded than expected
+	FindingServerUnhealthy HealthFindingKind = "server_unhealthy" // Server HEALTHZ status is not ok
+	FindingNoMetaLeader    HealthFindingKind = "no_meta_leader"   // JetStream meta group has no leader
+	FindingReplicaOffline  HealthFindingKind = "replica_offline"  // Meta group or stream replica is offline
+	FindingReplicaLagging  HealthFindingKind = "replica_lagging"  // Meta group or stream replica is not current or lags behind
+	FindingRouteMissing    HealthFindingKind = "route_missing"    // Route to a cluster peer is not established
+
+	FindingNoStreamLeader         HealthFindingKind = "no_stream_leader"         // Stream has no leader
+	FindingStreamUnderReplicated  HealthFindingKind = "stream_under_replicated"  // Stream has fewer peers than configured replicas
+	FindingConsumerPendingGrowing HealthFindingKind = "consumer_pending_growing" // Consumer pending count grew in every sample
+)
+
+// ClusterHealth combines HEALTHZ, VARZ, STATSZ and JSZ responses from all servers into a single cluster health report.
+// Report status is the most severe status of all findings.
+func (a *Analyzer) ClusterHealth(opts ClusterHealthOptions) (*ClusterHealthReport, error) {
+	healthz, err := a.client.HealthzPing(opts.Healthz)
+	if err != nil {
+		return nil, err
+	}
+	varz, err := a.client.VarzPing(VarzEventOptions{})
+	if err != nil {
+		return nil, err
+	}
+	statsz, err := a.client.ServerStatszPing(StatszEventOptions{})
+	if err != nil {
+		return nil, err
+	}
+	jsz, err := a.client.JszPing(JszEventOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if provider, ok := a.client.(ServerCountProvider); ok && opts.ExpectedServers <= 0 {
+		opts.ExpectedServers = provider.ExpectedServers()
+	}
+
+	return clusterHealth(opts, healthz, varz, statsz, jsz), nil
+}
+
+func clusterHealth(opts ClusterHealthOptions, healthz []HealthzResp, varz []VarzResp, statsz []ServerStatszResp, jsz []JSZResp) *ClusterHealthReport {
+	report := &ClusterHealthReport{}
+
+	responding := make(map[string]ServerInfo)
+	for _, resp := range healthz {
+		responding[resp.Server.ID] = resp.Server
+	}
+	for _, resp := range varz {
+		responding[resp.Server.ID] = resp.Server
+	}
+	for _, resp := range statsz {
+		responding[resp.Server.ID] = resp.Server
+	}
+	report.ServersResponding = len(responding)
+
+	meta := metaClusterInfo(jsz)
+
+	report.ServersExpected = opts.ExpectedServers
+	if report.ServersExpected <= 0 {
+		report.ServersExpected = report.ServersResponding
+		if meta != nil && meta.Size > report.ServersExpected {
+			report.ServersExpected = meta.Size
+		}
+	}
+	if report.ServersResponding < report.ServersExpected {
+		report.addFinding(HealthFinding{
+			Kind:    FindingServersMissing,
+			Status:  StatusUnavailable,
+			Message: fmt.Sprintf("%d of %d expected servers responded", report.ServersResponding, report.ServersExpected),
+		})
+	}
+
+	for _, resp := range healthz {
+		if resp.Healthz.Status == StatusOK {
+			continue
+		}
+		msg := fmt.Sprintf("health status is %q", resp.Healthz.Status)
+		if resp.Healthz.Error != "" {
+			msg = fmt.Sprintf("%s: %s", msg, resp.Healthz.Error)
+		}
+		report.addFinding(HealthFinding{
+			Kind:     FindingServerUnhealthy,
+			Status:   resp.Healthz.Status,
+			Server:   resp.Server.Name,
+			ServerID: resp.Server.ID,
+			Message:  msg,
+		})
+	}
+
+	if meta != nil {
+		report.MetaLeader = meta.Leader
+		if meta.Leader == "" {
+			report.addFinding(HealthFinding{
+				Kind:    FindingNoMetaLeader,
+				Status:  StatusError,
+				Message: fmt.Sprintf("JetStream meta group %q has no leader", meta.Name),
+			})
+		}
+		for _, peer := range meta.Replicas {
+			if peer == nil {
+				continue
+			}
+			switch {
+			case peer.Offline:
+				report.addFinding(HealthFinding{
+					Kind:    FindingReplicaOffline,
+					Status:  StatusUnavailable,
+					Server:  peer.Name,
+					Message: "JetStream meta group replica is offline",
+				})
+			case !peer.Current || (opts.MaxReplicaLag > 0 && peer.Lag > opts.MaxReplicaLag):
+				report.addFinding(HealthFinding{
+					Kind:    FindingReplicaLagging,
+					Status:  StatusUnavailable,
+					Server:  peer.Name,
+					Message: fmt.Sprintf("JetStream meta group replica is lagging (current: %t, lag: %d)", peer.Current, peer.Lag),
+				})
+			}
+		}
+	}
+
+	for _, finding := range missingRoutes(varz, statsz) {
+		report.addFinding(finding)
+	}
+
+	return report
+}
+
+func (r *ClusterHealthReport) addFinding(finding HealthFinding) {
+	r.Findings = append(r.Findings, finding)
+	if finding.Status > r.Status {
+		r.Status = finding.Status
+	}
+}
+
+// metaClusterInfo returns meta group information as reported by the meta leader.
+// If the leader did not respond, meta group information from any other server is returned.
+func metaClusterInfo(jsz []JSZResp) *MetaClusterInfo {
+	var meta *MetaClusterInfo
+	for _, resp := range jsz {
+		if resp.JSInfo.Disabled || resp.JSInfo.Meta == nil {
+			continue
+		}
+		if resp.JSInfo.Meta.Leader != "" && resp.JSInfo.Meta.Leader == resp.Server.Name {
+			return resp.JSInfo.Meta
+		}
+		if meta == nil || (meta.Leader == "" && resp.JSInfo.Meta.Leader != "") {
+			meta = resp.JSInfo.Meta
+		}
+	}
+	return meta
+}
+
+// missingRoutes reports servers which have fewer routes than configured in cluster URLs
+// and servers without a route to a responding peer from the same cluster.
+func missingRoutes(varz []VarzResp, statsz []ServerStatszResp) []HealthFinding {
+	findings := make([]HealthFinding, 0)
+
+	clusters := make(map[string][]string)
+	for _, resp := range varz {
+		if resp.Varz.Cluster.Name == "" {
+			continue
+		}
+		clusters[resp.Varz.Cluster.Name] = append(clusters[resp.Varz.Cluster.Name], resp.Server.Name)
+
+		// configured URLs may contain the server's own route URL, e.g. when all servers share the same config
+		var expected int
+		for _, u := range resp.Varz.Cluster.URLs {
+			if !isOwnRouteURL(resp, u) {
+				expected++
+			}
+		}
+		if resp.Varz.Routes < expected {
+			findings = append(findings, HealthFinding{
+				Kind:     FindingRouteMissing,
+				Status:   StatusUnavailable,
+				Server:   resp.Server.Name,
+				ServerID: resp.Server.ID,
+				Message:  fmt.Sprintf("%d routes established, %d route URLs to other servers configured", resp.Varz.Routes, expected),
+			})
+		}
+	}
+
+	for _, resp := range statsz {
+		peers, ok := clusters[resp.Server.Cluster]
+		if !ok {
+			continue
+		}
+		routed := make(map[string]struct{}, len(resp.Statsz.Routes))
+		for _, route := range resp.Statsz.Routes {
+			if route != nil {
+				routed[route.Name] = struct{}{}
+			}
+		}
+		missing := make([]string, 0)
+		for _, peer := range peers {
+			if peer == resp.Server.Name {
+				continue
+			}
+			if _, ok := routed[peer]; !ok {
+				missing = append(missing, peer)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		sort.Strings(missing)
+		findings = append(findings, HealthFinding{
+			Kind:     FindingRouteMissing,
+			Status:   StatusUnavailable,
+			Server:   resp.Server.Name,
+			ServerID: resp.Server.ID,
+			Message:  fmt.Sprintf("no route to cluster peers: %v", missing),
+		})
+	}
+	return findings
+}
+
+// isOwnRouteURL checks whether the route URL points to the server's own cluster listen address.
+// If the server listens on all interfaces, loopback addresses, the server's host
+// and host names starting with the server name (e.g. "nats-0.nats" for "nats-0") are considered its own.
+func isOwnRouteURL(resp VarzResp, routeURL string) bool {
+	host, port, err := net.SplitHostPort(routeURL)
+	if err != nil || port != strconv.Itoa(resp.Varz.Cluster.Port) {
+		return false
+	}
+	if host == resp.Varz.Cluster.Host {
+		return true
+	}
+	if listen := net.ParseIP(resp.Varz.Cluster.Host); resp.Varz.Cluster.Host != "" && (listen == nil || !listen.IsUnspecified()) {
+		return false
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+	name := resp.Server.Name
+	return host == resp.Server.Host || (name != "" && (host == name || strings.HasPrefix(host, name+".")))
+}
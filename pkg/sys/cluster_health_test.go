@@ -0,0 +1,245 @@
+package sys
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestClusterHealth(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	tests := []struct {
+		name           string
+		options        ClusterHealthOptions
+		expectedStatus HealthStatus
+		expectedKinds  []HealthFindingKind
+	}{
+		{
+			name:           "healthy cluster",
+			expectedStatus: StatusOK,
+		},
+		{
+			name:           "more servers expected",
+			options:        ClusterHealthOptions{ExpectedServers: 4},
+			expectedStatus: StatusUnavailable,
+			expectedKinds:  []HealthFindingKind{FindingServersMissing},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sys, err := NewSysClient(sysConn, ServerCount(3))
+			if err != nil {
+				t.Fatalf("Error creating system client: %s", err)
+			}
+
+			report, err := sys.ClusterHealth(test.options)
+			if err != nil {
+				t.Fatalf("Unable to fetch cluster health: %s", err)
+			}
+			if report.ServersResponding != 3 {
+				t.Fatalf("Invalid number of responding servers: %d; want: %d", report.ServersResponding, 3)
+			}
+			if report.MetaLeader == "" {
+				t.Fatalf("Expected meta leader in the report: %+v", report)
+			}
+			if report.Status != test.expectedStatus {
+				t.Fatalf("Invalid cluster status; want: %s; got: %s; findings: %+v", test.expectedStatus, report.Status, report.Findings)
+			}
+			if len(report.Findings) != len(test.expectedKinds) {
+				t.Fatalf("Invalid number of findings; want: %d; got: %+v", len(test.expectedKinds), report.Findings)
+			}
+			for i, kind := range test.expectedKinds {
+				if report.Findings[i].Kind != kind {
+					t.Fatalf("Invalid finding; want: %s; got: %+v", kind, report.Findings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClusterHealthFindings(t *testing.T) {
+	server := func(name string) ServerInfo {
+		return ServerInfo{Name: name, ID: "ID_" + name, Cluster: "C1"}
+	}
+	varz := func(name string, routes, port int) VarzResp {
+		return VarzResp{
+			Server: server(name),
+			Varz: Varz{
+				Name:    name,
+				Routes:  routes,
+				Cluster: ClusterOptsVarz{Name: "C1", Host: "0.0.0.0", Port: port, URLs: []string{"127.0.0.1:4223", "127.0.0.1:5223", "127.0.0.1:6223"}},
+			},
+		}
+	}
+	statsz := func(name string, peers ...string) ServerStatszResp {
+		resp := ServerStatszResp{Server: server(name)}
+		for _, peer := range peers {
+			resp.Statsz.Routes = append(resp.Statsz.Routes, &RouteStat{Name: peer})
+		}
+		return resp
+	}
+
+	healthz := []HealthzResp{
+		{Server: server("s1"), Healthz: Healthz{Status: StatusOK}},
+		{Server: server("s2"), Healthz: Healthz{Status: StatusError, Error: "JetStream is not current with the meta leader"}},
+		{Server: server("s3"), Healthz: Healthz{Status: StatusOK}},
+	}
+	varzResp := []VarzResp{varz("s1", 2, 4223), varz("s2", 2, 5223), varz("s3", 1, 6223)}
+	statszResp := []ServerStatszResp{statsz("s1", "s2", "s3"), statsz("s2", "s1", "s3"), statsz("s3", "s2")}
+	jsz := []JSZResp{
+		{
+			Server: server("s1"),
+			JSInfo: JSInfo{Meta: &MetaClusterInfo{Name: "C1", Size: 3, Replicas: []*PeerInfo{
+				{Name: "s2", Current: false, Lag: 10},
+				{Name: "s3", Current: true, Offline: true},
+				{Name: "s4", Current: true, Lag: 2},
+			}}},
+		},
+	}
+
+	report := clusterHealth(ClusterHealthOptions{}, healthz, varzResp, statszResp, jsz)
+	if report.Status != StatusError {
+		t.Fatalf("Invalid cluster status; want: %s; got: %s", StatusError, report.Status)
+	}
+	if report.ServersExpected != 3 || report.ServersResponding != 3 {
+		t.Fatalf("Invalid server counts: %+v", report)
+	}
+	expected := []struct {
+		kind   HealthFindingKind
+		server string
+	}{
+		{FindingServerUnhealthy, "s2"},
+		{FindingNoMetaLeader, ""},
+		{FindingReplicaLagging, "s2"},
+		{FindingReplicaOffline, "s3"},
+		{FindingRouteMissing, "s3"},
+		{FindingRouteMissing, "s3"},
+	}
+	if len(report.Findings) != len(expected) {
+		t.Fatalf("Invalid number of findings; want: %d; got: %+v", len(expected), report.Findings)
+	}
+	for i, want := range expected {
+		if report.Findings[i].Kind != want.kind || report.Findings[i].Server != want.server {
+			t.Fatalf("Invalid finding; want: %s (%s); got: %+v", want.kind, want.server, report.Findings[i])
+		}
+	}
+
+	// current replicas are only reported as lagging if they exceed configured lag
+	report = clusterHealth(ClusterHealthOptions{MaxReplicaLag: 1}, healthz, varzResp, statszResp, jsz)
+	var lagging []string
+	for _, finding := range report.Findings {
+		if finding.Kind == FindingReplicaLagging {
+			lagging = append(lagging, finding.Server)
+		}
+	}
+	if len(lagging) != 2 || lagging[0] != "s2" || lagging[1] != "s4" {
+		t.Fatalf("Invalid lagging replicas; want: [s2 s4]; got: %v", lagging)
+	}
+}
+
+func TestMissingRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  ServerInfo
+		cluster ClusterOptsVarz
+		routes  int
+		missing bool
+	}{
+		{
+			name:    "own URL excluded",
+			cluster: ClusterOptsVarz{Host: "0.0.0.0", Port: 4223, URLs: []string{"localhost:4223", "127.0.0.1:5223", "127.0.0.1:6223"}},
+			routes:  2,
+		},
+		{
+			name:    "own URL not configured",
+			cluster: ClusterOptsVarz{Host: "0.0.0.0", Port: 4223, URLs: []string{"127.0.0.1:5223", "127.0.0.1:6223"}},
+			routes:  1,
+			missing: true,
+		},
+		{
+			name:    "own URL with explicit listen address",
+			cluster: ClusterOptsVarz{Host: "10.0.0.1", Port: 6222, URLs: []string{"10.0.0.1:6222", "10.0.0.2:6222", "10.0.0.3:6222"}},
+			routes:  2,
+		},
+		{
+			name:    "same port on other hosts",
+			cluster: ClusterOptsVarz{Host: "10.0.0.1", Port: 6222, URLs: []string{"10.0.0.2:6222", "10.0.0.3:6222"}},
+			routes:  1,
+			missing: true,
+		},
+		{
+			name:    "own URL with server name as host",
+			server:  ServerInfo{Name: "nats-0"},
+			cluster: ClusterOptsVarz{Port: 6222, URLs: []string{"nats-0.nats:6222", "nats-1.nats:6222", "nats-2.nats:6222"}},
+			routes:  2,
+		},
+		{
+			name:    "other servers with server name prefix",
+			server:  ServerInfo{Name: "nats-1"},
+			cluster: ClusterOptsVarz{Port: 6222, URLs: []string{"nats-10.nats:6222", "nats-2.nats:6222"}},
+			routes:  1,
+			missing: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.cluster.Name = "C1"
+			varz := []VarzResp{{Server: test.server, Varz: Varz{Routes: test.routes, Cluster: test.cluster}}}
+			findings := missingRoutes(varz, nil)
+			if missing := len(findings) > 0; missing != test.missing {
+				t.Fatalf("Invalid route findings; want missing: %t; got: %+v", test.missing, findings)
+			}
+		})
+	}
+}
+
+func TestClusterHealthExpectedServers(t *testing.T) {
+	snapshot := &Snapshot{ExpectedServers: 3}
+	for _, name := range []string{"s1", "s2"} {
+		server := ServerInfo{Name: name, ID: "ID_" + name}
+		snapshot.Healthz = append(snapshot.Healthz, HealthzResp{Server: server, Healthz: Healthz{Status: StatusOK}})
+		snapshot.Varz = append(snapshot.Varz, VarzResp{Server: server})
+	}
+	source := NewSnapshotSource(snapshot)
+	cached, err := NewCachedClient(source)
+	if err != nil {
+		t.Fatalf("Unable to create cached client: %s", err)
+	}
+
+	for name, client := range map[string]SysClient{
+		"snapshot": source,
+		"cached":   cached,
+	} {
+		t.Run(name, func(t *testing.T) {
+			report, err := NewAnalyzer(client).ClusterHealth(ClusterHealthOptions{})
+			if err != nil {
+				t.Fatalf("Unable to check cluster health: %s", err)
+			}
+			if report.ServersExpected != 3 || report.ServersResponding != 2 {
+				t.Fatalf("Invalid server counts: %+v", report)
+			}
+			if len(report.Findings) != 1 || report.Findings[0].Kind != FindingServersMissing {
+				t.Fatalf("Invalid findings: %+v", report.Findings)
+			}
+		})
+	}
+}
@@ -163,13 +163,12 @@ func (s *System) Connz(id string, opts ConnzEventOptions) (*ConnzResp, error) {
 	if id == "" {
 		return nil, fmt.Errorf("%w: server id cannot be empty", ErrValidation)
 	}
-	conn := s.nc
 	subj := fmt.Sprintf(srvConnzSubj, id)
 	payload, err := json.Marshal(opts)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := conn.Request(subj, payload, s.opts.timeout)
+	resp, err := s.request(subj, payload)
 	if err != nil {
 		if errors.Is(err, nats.ErrNoResponders) {
 			return nil, fmt.Errorf("%w: %s", ErrInvalidServerID, id)
@@ -0,0 +1,279 @@
+package sys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	DefaultConsumerLagInterval       = 10 * time.Second
+	DefaultConsumerLagStallThreshold = time.Minute
+)
+
+type (
+	// ConsumerLagReport lists lag of all JetStream consumers, sorted by account, stream and consumer name.
+	ConsumerLagReport struct {
+		Time      time.Time     `json:"time"`
+		Consumers []ConsumerLag `json:"consumers"`
+	}
+
+	// ConsumerLag describes how far behind its stream a consumer is.
+	//
+	// Lag is the number of messages not yet processed, i.e. pending delivery or acknowledgement.
+	// Trend, LagRate (messages per second) and Stalled are only set by WatchConsumerLag.
+	// A consumer is stalled if it has lag, but neither delivered nor acknowledged messages
+	// for at least the stall threshold.
+	ConsumerLag struct {
+		Account        string        `json:"account"`
+		Stream         string        `json:"stream"`
+		Consumer       string        `json:"consumer"`
+		Leader         string        `json:"leader,omitempty"`
+		NumPending     uint64        `json:"num_pending"`
+		NumAckPending  int           `json:"num_ack_pending"`
+		NumRedelivered int           `json:"num_redelivered"`
+		Delivered      uint64        `json:"delivered_stream_seq"`
+		AckFloor       uint64        `json:"ack_floor_stream_seq"`
+		Lag            uint64        `json:"lag"`
+		Trend          LagTrend      `json:"trend,omitempty"`
+		LagRate        float64       `json:"lag_rate,omitempty"`
+		Stalled        bool          `json:"stalled,omitempty"`
+		StalledFor     time.Duration `json:"stalled_for,omitempty"`
+	}
+
+	LagTrend string
+
+	// ConsumerLagEvent is sent by WatchConsumerLag after each poll.
+	ConsumerLagEvent struct {
+		Report *ConsumerLagReport
+		Err    error
+	}
+
+	// ConsumerLagOptions are options passed to ConsumerLag and WatchConsumerLag
+	ConsumerLagOptions struct {
+		// Account limits the report to consumers from a single account.
+		Account string
+
+		// LeaderOnly requests consumer information from the JetStream meta leader only.
+		// This avoids collecting the same consumers from each replica, but only streams
+		// with a replica on the meta leader are reported, and counts of consumers led
+		// by other servers may be stale. ErrNoMetaLeader is returned if the meta leader
+		// did not respond, e.g. during leader election or if JetStream is disabled.
+		// Otherwise, all servers are queried and the consumer leader's view is used.
+		LeaderOnly bool
+
+		// Interval is the time between polls in watch mode, DefaultConsumerLagInterval by default.
+		Interval time.Duration
+
+		// StallThreshold is the time after which a consumer making no progress is reported as stalled,
+		// DefaultConsumerLagStallThreshold by default.
+		StallThreshold time.Duration
+	}
+
+	consumerKey struct {
+		stream streamKey
+		name   string
+	}
+
+	consumerProgress struct {
+		time       time.Time
+		lag        uint64
+		delivered  uint64
+		ackFloor   uint64
+		progressAt time.Time
+	}
+
+	consumerLagTracker struct {
+		opts     ConsumerLagOptions
+		jsz      func() ([]JSZResp, error)
+		progress map[consumerKey]consumerProgress
+	}
+)
+
+// Possible lag trends
+const (
+	LagGrowing   LagTrend = "growing"
+	LagShrinking LagTrend = "shrinking"
+	LagSteady    LagTrend = "steady"
+)
+
+// ConsumerLag reports lag of all JetStream consumers.
+func (a *Analyzer) ConsumerLag(opts ConsumerLagOptions) (*ConsumerLagReport, error) {
+	jsz, err := a.consumerJsz(opts)
+	if err != nil {
+		return nil, err
+	}
+	return consumerLag(time.Now().UTC(), jsz), nil
+}
+
+// WatchConsumerLag keeps polling consumer lag until the context is done, tracking lag trends and stalled consumers.
+// Trends are reported starting with the second poll.
+// Returned channel is closed when the context is done.
+func (a *Analyzer) WatchConsumerLag(ctx context.Context, opts ConsumerLagOptions) <-chan ConsumerLagEvent {
+	tracker := a.newConsumerLagTracker(opts)
+	events := make(chan ConsumerLagEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(tracker.opts.Interval)
+		defer ticker.Stop()
+		for {
+			report, err := tracker.poll(time.Now().UTC())
+			select {
+			case events <- ConsumerLagEvent{Report: report, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// consumerJsz requests JSZ with consumer details, from the meta leader only if opts.LeaderOnly is set.
+// The meta leader is resolved first, as servers other than the meta leader do not respond to leader only requests.
+func (a *Analyzer) consumerJsz(opts ConsumerLagOptions) ([]JSZResp, error) {
+	jszOpts := JszEventOptions{
+		JszOptions: JszOptions{
+			Account:    opts.Account,
+			Accounts:   true,
+			Streams:    true,
+			Consumer:   true,
+			LeaderOnly: opts.LeaderOnly,
+		},
+	}
+	if !opts.LeaderOnly {
+		return a.client.JszPing(jszOpts)
+	}
+
+	jsz, err := a.client.JszPing(JszEventOptions{})
+	if err != nil {
+		return nil, err
+	}
+	leader := metaLeader(jsz)
+	if leader == nil {
+		for _, resp := range jsz {
+			// without clustering, every JetStream server is its own leader
+			if !resp.JSInfo.Disabled && resp.JSInfo.Meta == nil {
+				return a.client.JszPing(jszOpts)
+			}
+		}
+		return nil, ErrNoMetaLeader
+	}
+	resp, err := a.client.Jsz(leader.Server.ID, jszOpts)
+	if err != nil {
+		// leadership moved since the meta leader was resolved
+		if errors.Is(err, nats.ErrTimeout) {
+			return nil, fmt.Errorf("%w: %s", ErrNoMetaLeader, leader.Server.Name)
+		}
+		return nil, err
+	}
+	return []JSZResp{*resp}, nil
+}
+
+func consumerLag(now time.Time, jsz []JSZResp) *ConsumerLagReport {
+	report := &ConsumerLagReport{
+		Time:      now,
+		Consumers: make([]ConsumerLag, 0),
+	}
+	for key, consumers := range dedupConsumers(jsz) {
+		for name, info := range consumers {
+			lag := ConsumerLag{
+				Account:        key.account,
+				Stream:         key.name,
+				Consumer:       name,
+				NumPending:     info.NumPending,
+				NumAckPending:  info.NumAckPending,
+				NumRedelivered: info.NumRedelivered,
+				Delivered:      info.Delivered.Stream,
+				AckFloor:       info.AckFloor.Stream,
+				Lag:            info.NumPending,
+			}
+			if info.NumAckPending > 0 {
+				lag.Lag += uint64(info.NumAckPending)
+			}
+			if info.Cluster != nil {
+				lag.Leader = info.Cluster.Leader
+			}
+			report.Consumers = append(report.Consumers, lag)
+		}
+	}
+	sort.Slice(report.Consumers, func(i, j int) bool {
+		a, b := report.Consumers[i], report.Consumers[j]
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		if a.Stream != b.Stream {
+			return a.Stream < b.Stream
+		}
+		return a.Consumer < b.Consumer
+	})
+	return report
+}
+
+func (a *Analyzer) newConsumerLagTracker(opts ConsumerLagOptions) *consumerLagTracker {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultConsumerLagInterval
+	}
+	if opts.StallThreshold <= 0 {
+		opts.StallThreshold = DefaultConsumerLagStallThreshold
+	}
+	return &consumerLagTracker{
+		opts:     opts,
+		jsz:      func() ([]JSZResp, error) { return a.consumerJsz(opts) },
+		progress: make(map[consumerKey]consumerProgress),
+	}
+}
+
+// poll fetches consumer lag and compares it with the previous poll.
+// Consumers which are no longer reported are forgotten.
+func (t *consumerLagTracker) poll(now time.Time) (*ConsumerLagReport, error) {
+	jsz, err := t.jsz()
+	if err != nil {
+		return nil, err
+	}
+	report := consumerLag(now, jsz)
+
+	progress := make(map[consumerKey]consumerProgress, len(report.Consumers))
+	for i := range report.Consumers {
+		lag := &report.Consumers[i]
+		key := consumerKey{stream: streamKey{account: lag.Account, name: lag.Stream}, name: lag.Consumer}
+		current := consumerProgress{
+			time:       now,
+			lag:        lag.Lag,
+			delivered:  lag.Delivered,
+			ackFloor:   lag.AckFloor,
+			progressAt: now,
+		}
+		if prev, ok := t.progress[key]; ok {
+			if elapsed := now.Sub(prev.time).Seconds(); elapsed > 0 {
+				lag.LagRate = (float64(lag.Lag) - float64(prev.lag)) / elapsed
+			}
+			switch {
+			case lag.Lag > prev.lag:
+				lag.Trend = LagGrowing
+			case lag.Lag < prev.lag:
+				lag.Trend = LagShrinking
+			default:
+				lag.Trend = LagSteady
+			}
+			if lag.Delivered == prev.delivered && lag.AckFloor == prev.ackFloor {
+				current.progressAt = prev.progressAt
+			}
+		}
+		if lag.Lag > 0 && now.Sub(current.progressAt) >= t.opts.StallThreshold {
+			lag.Stalled = true
+			lag.StalledFor = now.Sub(current.progressAt)
+		}
+		progress[key] = current
+	}
+	t.progress = progress
+	return report, nil
+}
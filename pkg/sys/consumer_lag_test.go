@@ -0,0 +1,216 @@
+package sys
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func TestConsumerLag(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	nc, err := nats.Connect(strings.Join(urls, ","))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Error getting JetStream context: %v", err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "s1", Subjects: []string{"foo"}, Replicas: 3}); err != nil {
+		t.Fatalf("Error creating stream: %v", err)
+	}
+	if _, err := js.AddConsumer("s1", &nats.ConsumerConfig{Durable: "c1", AckPolicy: nats.AckExplicitPolicy}); err != nil {
+		t.Fatalf("Error creating consumer: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := js.Publish("foo", []byte("msg")); err != nil {
+			t.Fatalf("Error publishing message: %v", err)
+		}
+	}
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	for _, leaderOnly := range []bool{false, true} {
+		report, err := sys.ConsumerLag(ConsumerLagOptions{LeaderOnly: leaderOnly})
+		if err != nil {
+			t.Fatalf("Unable to fetch consumer lag: %s", err)
+		}
+		if len(report.Consumers) != 1 {
+			t.Fatalf("Invalid number of consumers: %d; want: %d", len(report.Consumers), 1)
+		}
+		lag := report.Consumers[0]
+		if lag.Account != "JS" || lag.Stream != "s1" || lag.Consumer != "c1" || lag.Leader == "" {
+			t.Fatalf("Invalid consumer lag (leader only: %t): %+v", leaderOnly, lag)
+		}
+		// meta leader reports stale counts for consumers led by other servers
+		if !leaderOnly && (lag.Lag != 5 || lag.NumPending != 5) {
+			t.Fatalf("Invalid consumer lag; want: %d; got: %+v", 5, lag)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := sys.WatchConsumerLag(ctx, ConsumerLagOptions{Interval: 100 * time.Millisecond})
+	for i := 0; i < 2; i++ {
+		event := <-events
+		if event.Err != nil {
+			t.Fatalf("Unexpected error: %s", event.Err)
+		}
+		if i == 1 && event.Report.Consumers[0].Trend != LagSteady {
+			t.Fatalf("Invalid lag trend: %+v", event.Report.Consumers[0])
+		}
+	}
+}
+
+func TestConsumerLagTracker(t *testing.T) {
+	consumer := func(server, leader string, pending uint64, ackPending int, delivered uint64) JSZResp {
+		return JSZResp{
+			Server: ServerInfo{Name: server},
+			JSInfo: JSInfo{AccountDetails: []*AccountDetail{{
+				Name: "ACC",
+				Streams: []StreamDetail{{
+					Name: "ORDERS",
+					Consumer: []*nats.ConsumerInfo{{
+						Name:          "c1",
+						NumPending:    pending,
+						NumAckPending: ackPending,
+						Delivered:     nats.SequenceInfo{Stream: delivered},
+						Cluster:       &nats.ClusterInfo{Leader: leader},
+					}},
+				}},
+			}}},
+		}
+	}
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name     string
+		offset   time.Duration
+		jsz      []JSZResp
+		expected ConsumerLag
+	}{
+		{
+			name: "first poll uses consumer leader",
+			jsz: []JSZResp{
+				consumer("s1", "s2", 100, 0, 0),
+				consumer("s2", "s2", 10, 2, 5),
+			},
+			expected: ConsumerLag{NumPending: 10, NumAckPending: 2, Delivered: 5, Lag: 12},
+		},
+		{
+			name:     "growing lag",
+			offset:   10 * time.Second,
+			jsz:      []JSZResp{consumer("s2", "s2", 30, 2, 10)},
+			expected: ConsumerLag{NumPending: 30, NumAckPending: 2, Delivered: 10, Lag: 32, Trend: LagGrowing, LagRate: 2},
+		},
+		{
+			name:     "no progress",
+			offset:   40 * time.Second,
+			jsz:      []JSZResp{consumer("s2", "s2", 30, 2, 10)},
+			expected: ConsumerLag{NumPending: 30, NumAckPending: 2, Delivered: 10, Lag: 32, Trend: LagSteady},
+		},
+		{
+			name:     "stalled",
+			offset:   70 * time.Second,
+			jsz:      []JSZResp{consumer("s2", "s2", 40, 2, 10)},
+			expected: ConsumerLag{NumPending: 40, NumAckPending: 2, Delivered: 10, Lag: 42, Trend: LagGrowing, LagRate: 1.0 / 3, Stalled: true, StalledFor: time.Minute},
+		},
+		{
+			name:     "progress resets stall",
+			offset:   80 * time.Second,
+			jsz:      []JSZResp{consumer("s2", "s2", 20, 2, 30)},
+			expected: ConsumerLag{NumPending: 20, NumAckPending: 2, Delivered: 30, Lag: 22, Trend: LagShrinking, LagRate: -2},
+		},
+	}
+
+	var jsz []JSZResp
+	tracker := &consumerLagTracker{
+		opts:     ConsumerLagOptions{StallThreshold: time.Minute},
+		jsz:      func() ([]JSZResp, error) { return jsz, nil },
+		progress: make(map[consumerKey]consumerProgress),
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			jsz = step.jsz
+			report, err := tracker.poll(start.Add(step.offset))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if len(report.Consumers) != 1 {
+				t.Fatalf("Invalid number of consumers: %d; want: %d", len(report.Consumers), 1)
+			}
+			step.expected.Account, step.expected.Stream, step.expected.Consumer, step.expected.Leader = "ACC", "ORDERS", "c1", "s2"
+			if report.Consumers[0] != step.expected {
+				t.Fatalf("Invalid consumer lag;\nwant: %+v;\ngot:  %+v", step.expected, report.Consumers[0])
+			}
+		})
+	}
+}
+
+func TestConsumerLagWithoutMetaLeader(t *testing.T) {
+	opts, err := server.ProcessConfigFile("./testdata/s1.conf")
+	if err != nil {
+		t.Fatalf("Error processing config file: %v", err)
+	}
+	opts.NoLog = true
+	opts.Port = -1
+	opts.JetStream = false
+	opts.Cluster = server.ClusterOpts{}
+	opts.Routes = nil
+	srv, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatalf("Error creating server: %s", err)
+	}
+	srv.Start()
+	defer srv.Shutdown()
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatal("Unable to start NATS Server")
+	}
+
+	sysConn, err := nats.Connect(srv.ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+	sys, err := NewSysClient(sysConn, ServerCount(1))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	if _, err := sys.ConsumerLag(ConsumerLagOptions{LeaderOnly: true}); !errors.Is(err, ErrNoMetaLeader) {
+		t.Fatalf("Expected error: %v; got: %v", ErrNoMetaLeader, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := sys.WatchConsumerLag(ctx, ConsumerLagOptions{LeaderOnly: true, Interval: 100 * time.Millisecond})
+	select {
+	case event := <-events:
+		if !errors.Is(event.Err, ErrNoMetaLeader) {
+			t.Fatalf("Expected error: %v; got: %v", ErrNoMetaLeader, event.Err)
+		}
+	case <-ctx.Done():
+		t.Fatalf("Timeout waiting for consumer lag event")
+	}
+}
@@ -0,0 +1,116 @@
+package sys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	srvKickSubj = "$SYS.REQ.SERVER.%s.KICK"
+	srvLDMSubj  = "$SYS.REQ.SERVER.%s.LDM"
+)
+
+// ErrControlDisabled is returned by control operations if the client was created without AllowControl option.
+var ErrControlDisabled = errors.New("control operations are disabled")
+
+type (
+	// ControlResp is the response to a control request, e.g. KickClient or LameDuckClient.
+	ControlResp struct {
+		Server ServerInfo      `json:"server"`
+		Error  *ServerAPIError `json:"error,omitempty"`
+	}
+
+	// KickClientResp is the response to KickClient.
+	KickClientResp struct {
+		ControlResp
+		Cid uint64 `json:"-"`
+	}
+
+	// LameDuckClientResp is the response to LameDuckClient.
+	LameDuckClientResp struct {
+		ControlResp
+		Cid uint64 `json:"-"`
+	}
+
+	// ServerAPIError is an error returned by the server in response to a system request.
+	ServerAPIError struct {
+		Code        int    `json:"code"`
+		Description string `json:"description,omitempty"`
+	}
+
+	clientControlReq struct {
+		Cid uint64 `json:"cid"`
+	}
+)
+
+func (e *ServerAPIError) Error() string {
+	return fmt.Sprintf("server error (%d): %s", e.Code, e.Description)
+}
+
+// AllowControl enables operations which modify server state, e.g. KickClient or LameDuckClient.
+// Clients created without this option are read-only.
+func AllowControl() SysClientOpt {
+	return func(opts *sysClientOpts) error {
+		opts.allowControl = true
+		return nil
+	}
+}
+
+// KickClient disconnects the client connection with given CID on a server.
+// Requires AllowControl option.
+func (s *System) KickClient(id string, cid uint64) (*KickClientResp, error) {
+	var resp KickClientResp
+	if err := s.controlRequest(srvKickSubj, id, clientControlReq{Cid: cid}, &resp.ControlResp); err != nil {
+		return nil, err
+	}
+	resp.Cid = cid
+	return &resp, nil
+}
+
+// LameDuckClient sends lame duck mode notification to the client connection with given CID on a server,
+// so that the client can reconnect to a different server.
+// Requires AllowControl option.
+func (s *System) LameDuckClient(id string, cid uint64) (*LameDuckClientResp, error) {
+	var resp LameDuckClientResp
+	if err := s.controlRequest(srvLDMSubj, id, clientControlReq{Cid: cid}, &resp.ControlResp); err != nil {
+		return nil, err
+	}
+	resp.Cid = cid
+	return &resp, nil
+}
+
+// controlRequest sends a control request to a single server.
+// Error included in server response is returned as *ServerAPIError.
+func (s *System) controlRequest(subjFormat, id string, req interface{}, resp *ControlResp) error {
+	if !s.opts.allowControl {
+		return ErrControlDisabled
+	}
+	if id == "" {
+		return fmt.Errorf("%w: server id cannot be empty", ErrValidation)
+	}
+	var payload []byte
+	if req != nil {
+		var err error
+		payload, err = json.Marshal(req)
+		if err != nil {
+			return err
+		}
+	}
+	msg, err := s.nc.Request(fmt.Sprintf(subjFormat, id), payload, s.opts.timeout)
+	if err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return fmt.Errorf("%w: %s", ErrInvalidServerID, id)
+		}
+		return err
+	}
+	if err := json.Unmarshal(msg.Data, resp); err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package sys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestKickClient(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	// servers used in tests do not handle control requests, so responses are simulated
+	sub, err := sysConn.Subscribe(fmt.Sprintf(srvKickSubj, "fake"), func(msg *nats.Msg) {
+		var req clientControlReq
+		resp := ControlResp{Server: ServerInfo{ID: "fake", Name: "fake"}}
+		if err := json.Unmarshal(msg.Data, &req); err != nil || req.Cid != 1 {
+			resp.Error = &ServerAPIError{Code: 404, Description: "client not found"}
+		}
+		data, _ := json.Marshal(resp)
+		msg.Respond(data)
+	})
+	if err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	defer sub.Unsubscribe()
+
+	readOnly, err := NewSysClient(sysConn)
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	if _, err := readOnly.KickClient("fake", 1); !errors.Is(err, ErrControlDisabled) {
+		t.Fatalf("Expected error: %s; got: %v", ErrControlDisabled, err)
+	}
+
+	sys, err := NewSysClient(sysConn, AllowControl())
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		id        string
+		cid       uint64
+		withError error
+		apiError  bool
+	}{
+		{name: "kick client", id: "fake", cid: 1},
+		{name: "client not found", id: "fake", cid: 2, apiError: true},
+		{name: "empty server id", id: "", cid: 1, withError: ErrValidation},
+		{name: "unknown server", id: "unknown", cid: 1, withError: ErrInvalidServerID},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := sys.KickClient(test.id, test.cid)
+			if test.withError != nil {
+				if !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error: %s; got: %v", test.withError, err)
+				}
+				return
+			}
+			if test.apiError {
+				var apiErr *ServerAPIError
+				if !errors.As(err, &apiErr) || apiErr.Code != 404 {
+					t.Fatalf("Expected server API error; got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unable to kick client: %s", err)
+			}
+			if resp.Server.ID != test.id || resp.Cid != test.cid {
+				t.Fatalf("Invalid response: %+v", resp)
+			}
+		})
+	}
+}
+
+func TestLameDuckClient(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	sysConn, err := nats.Connect(c.servers[0].ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	sub, err := sysConn.Subscribe(fmt.Sprintf(srvLDMSubj, "fake"), func(msg *nats.Msg) {
+		data, _ := json.Marshal(ControlResp{Server: ServerInfo{ID: "fake"}})
+		msg.Respond(data)
+	})
+	if err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	defer sub.Unsubscribe()
+
+	readOnly, err := NewSysClient(sysConn)
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	if _, err := readOnly.LameDuckClient("fake", 1); !errors.Is(err, ErrControlDisabled) {
+		t.Fatalf("Expected error: %s; got: %v", ErrControlDisabled, err)
+	}
+
+	sys, err := NewSysClient(sysConn, AllowControl())
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	resp, err := sys.LameDuckClient("fake", 5)
+	if err != nil {
+		t.Fatalf("Unable to put client in lame duck mode: %s", err)
+	}
+	if resp.Server.ID != "fake" || resp.Cid != 5 {
+		t.Fatalf("Invalid response: %+v", resp)
+	}
+}
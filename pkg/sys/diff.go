@@ -0,0 +1,336 @@
+package sys
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// Diff lists changes between two monitoring responses, ordered by path.
+	Diff struct {
+		Changes []Change `json:"changes"`
+	}
+
+	// Change is a single difference between two monitoring responses.
+	//
+	// Path consists of JSON field names, e.g. "cluster.urls".
+	// Entries of keyed collections are identified by key in brackets,
+	// e.g. "connections[12].in_msgs" or "account_details[ACC].stream_detail[ORDERS].state.bytes".
+	// For counters (e.g. "in_msgs"), Delta holds the difference between the new and the old value.
+	Change struct {
+		Path  string      `json:"path"`
+		Kind  ChangeKind  `json:"kind"`
+		Old   interface{} `json:"old,omitempty"`
+		New   interface{} `json:"new,omitempty"`
+		Delta *float64    `json:"delta,omitempty"`
+	}
+
+	ChangeKind string
+)
+
+// Possible change kinds
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "changed"
+)
+
+var (
+	// diffCollectionKeys are JSON names of fields identifying entries of collections, in order of precedence.
+	// Collections without a key, or with duplicate keys, are compared as a whole.
+	diffCollectionKeys = []string{"cid", "rid", "name"}
+
+	// diffCounters are fields reported as deltas, matched by field name or by the last two path segments.
+	diffCounters = map[string]struct{}{
+		"in_msgs":              {},
+		"out_msgs":             {},
+		"in_bytes":             {},
+		"out_bytes":            {},
+		"total_connections":    {},
+		"slow_consumers":       {},
+		"pinned_account_fails": {},
+		"msgs":                 {},
+		"api.total":            {},
+		"api.errors":           {},
+	}
+
+	// diffIgnored are fields changing on every request.
+	diffIgnored = map[string]struct{}{
+		"now":    {},
+		"uptime": {},
+		"idle":   {},
+	}
+
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// DiffVarz compares two VARZ responses, e.g. taken before and after a deployment.
+func DiffVarz(old, new *Varz) *Diff {
+	return diffValues(old, new)
+}
+
+// DiffConnz compares two CONNZ responses. Connections are matched by CID.
+func DiffConnz(old, new *Connz) *Diff {
+	return diffValues(old, new)
+}
+
+// DiffJSInfo compares two JSZ responses. Accounts, streams and consumers are matched by name.
+func DiffJSInfo(old, new *JSInfo) *Diff {
+	return diffValues(old, new)
+}
+
+func diffValues(old, new interface{}) *Diff {
+	d := &Diff{Changes: make([]Change, 0)}
+	d.diff("", reflect.ValueOf(old), reflect.ValueOf(new))
+	sort.SliceStable(d.Changes, func(i, j int) bool {
+		return d.Changes[i].Path < d.Changes[j].Path
+	})
+	return d
+}
+
+// Changed checks whether any changes were found.
+func (d *Diff) Changed() bool {
+	return len(d.Changes) > 0
+}
+
+// WriteJSON writes the changes as JSON.
+func (d *Diff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// WriteText writes each change in a single line, prefixed with "+", "-" or "~" for added, removed and changed values.
+func (d *Diff) WriteText(w io.Writer) error {
+	for _, change := range d.Changes {
+		if _, err := fmt.Fprintln(w, change.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %v", c.Path, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %v", c.Path, c.Old)
+	}
+	if c.Delta != nil {
+		return fmt.Sprintf("~ %s: %v -> %v (%+g)", c.Path, c.Old, c.New, *c.Delta)
+	}
+	return fmt.Sprintf("~ %s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+func (d *Diff) diff(path string, old, new reflect.Value) {
+	if old.Kind() == reflect.Ptr || old.Kind() == reflect.Interface {
+		switch {
+		case old.IsNil() && new.IsNil():
+			return
+		case old.IsNil():
+			d.Changes = append(d.Changes, Change{Path: path, Kind: ChangeAdded, New: new.Elem().Interface()})
+			return
+		case new.IsNil():
+			d.Changes = append(d.Changes, Change{Path: path, Kind: ChangeRemoved, Old: old.Elem().Interface()})
+			return
+		}
+		if old.Elem().Type() != new.Elem().Type() {
+			d.modified(path, old.Elem(), new.Elem())
+			return
+		}
+		d.diff(path, old.Elem(), new.Elem())
+		return
+	}
+
+	switch {
+	case old.Type() == timeType:
+		if !old.Interface().(time.Time).Equal(new.Interface().(time.Time)) {
+			d.modified(path, old, new)
+		}
+	case old.Kind() == reflect.Struct:
+		d.diffStruct(path, old, new)
+	case old.Kind() == reflect.Slice && diffCollectionKey(old.Type().Elem()) != nil:
+		d.diffCollection(path, old, new)
+	case old.Kind() == reflect.Map:
+		d.diffMap(path, old, new)
+	case old.Kind() == reflect.Slice && old.Len() == 0 && new.Len() == 0:
+		// nil and empty slices are equivalent
+	default:
+		if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+			d.modified(path, old, new)
+		}
+	}
+}
+
+func (d *Diff) modified(path string, old, new reflect.Value) {
+	change := Change{Path: path, Kind: ChangeModified, Old: old.Interface(), New: new.Interface()}
+	if isDiffCounter(path) {
+		if oldNum, ok := numericValue(old); ok {
+			if newNum, ok := numericValue(new); ok {
+				delta := newNum - oldNum
+				change.Delta = &delta
+			}
+		}
+	}
+	d.Changes = append(d.Changes, change)
+}
+
+func (d *Diff) diffStruct(path string, old, new reflect.Value) {
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+		// embedded structs are inlined in JSON
+		if field.Anonymous && name == "" {
+			d.diff(path, old.Field(i), new.Field(i))
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := diffIgnored[name]; ok {
+			continue
+		}
+		d.diff(joinPath(path, name), old.Field(i), new.Field(i))
+	}
+}
+
+func (d *Diff) diffCollection(path string, old, new reflect.Value) {
+	oldEntries, okOld := keyedEntries(old)
+	newEntries, okNew := keyedEntries(new)
+	if !okOld || !okNew {
+		if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+			d.modified(path, old, new)
+		}
+		return
+	}
+	for key, oldEntry := range oldEntries {
+		entryPath := fmt.Sprintf("%s[%s]", path, key)
+		newEntry, ok := newEntries[key]
+		if !ok {
+			d.Changes = append(d.Changes, Change{Path: entryPath, Kind: ChangeRemoved, Old: reflect.Indirect(oldEntry).Interface()})
+			continue
+		}
+		d.diff(entryPath, oldEntry, newEntry)
+	}
+	for key, newEntry := range newEntries {
+		if _, ok := oldEntries[key]; !ok {
+			entryPath := fmt.Sprintf("%s[%s]", path, key)
+			d.Changes = append(d.Changes, Change{Path: entryPath, Kind: ChangeAdded, New: reflect.Indirect(newEntry).Interface()})
+		}
+	}
+}
+
+func (d *Diff) diffMap(path string, old, new reflect.Value) {
+	keys := make(map[string]reflect.Value)
+	for _, key := range old.MapKeys() {
+		keys[fmt.Sprint(key.Interface())] = key
+	}
+	for _, key := range new.MapKeys() {
+		keys[fmt.Sprint(key.Interface())] = key
+	}
+	for name, key := range keys {
+		entryPath := joinPath(path, name)
+		oldEntry, newEntry := old.MapIndex(key), new.MapIndex(key)
+		switch {
+		case !oldEntry.IsValid():
+			d.Changes = append(d.Changes, Change{Path: entryPath, Kind: ChangeAdded, New: newEntry.Interface()})
+		case !newEntry.IsValid():
+			d.Changes = append(d.Changes, Change{Path: entryPath, Kind: ChangeRemoved, Old: oldEntry.Interface()})
+		default:
+			d.diff(entryPath, oldEntry, newEntry)
+		}
+	}
+}
+
+// diffCollectionKey returns a function extracting the key of a collection entry of given type,
+// or nil if entries are not keyed.
+func diffCollectionKey(typ reflect.Type) func(reflect.Value) (string, bool) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	for _, key := range diffCollectionKeys {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() || jsonFieldName(field) != key {
+				continue
+			}
+			idx := i
+			return func(v reflect.Value) (string, bool) {
+				v = reflect.Indirect(v)
+				if !v.IsValid() {
+					return "", false
+				}
+				return fmt.Sprint(v.Field(idx).Interface()), true
+			}
+		}
+	}
+	return nil
+}
+
+// keyedEntries maps collection entries by key. It returns false if any entry is nil or keys are not unique.
+func keyedEntries(v reflect.Value) (map[string]reflect.Value, bool) {
+	key := diffCollectionKey(v.Type().Elem())
+	entries := make(map[string]reflect.Value, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		k, ok := key(v.Index(i))
+		if !ok {
+			return nil, false
+		}
+		if _, ok := entries[k]; ok {
+			return nil, false
+		}
+		entries[k] = v.Index(i)
+	}
+	return entries, true
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func isDiffCounter(path string) bool {
+	segments := strings.Split(path, ".")
+	if _, ok := diffCounters[segments[len(segments)-1]]; ok {
+		return true
+	}
+	if len(segments) < 2 {
+		return false
+	}
+	_, ok := diffCounters[strings.Join(segments[len(segments)-2:], ".")]
+	return ok
+}
+
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
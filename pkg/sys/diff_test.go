@@ -0,0 +1,132 @@
+package sys
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestDiffVarz(t *testing.T) {
+	old := &Varz{
+		Version: "2.9.14",
+		Now:     time.Now(),
+		Cluster: ClusterOptsVarz{Name: "C1", URLs: []string{"a:6222", "b:6222"}},
+		Gateway: GatewayOptsVarz{
+			Gateways: []RemoteGatewayOptsVarz{{Name: "C2"}, {Name: "C3", URLs: []string{"c3:7222"}}},
+		},
+		JetStream:    JetStreamVarz{Config: &JetStreamConfig{MaxStore: 1024}},
+		InMsgs:       10,
+		HTTPReqStats: map[string]uint64{"/varz": 1},
+		Tags:         nil,
+	}
+	new := &Varz{
+		Version: "2.9.15",
+		Now:     time.Now().Add(time.Minute),
+		Cluster: ClusterOptsVarz{Name: "C1", URLs: []string{"a:6222", "c:6222"}},
+		Gateway: GatewayOptsVarz{
+			Gateways: []RemoteGatewayOptsVarz{{Name: "C3", URLs: []string{"c3:7223"}}, {Name: "C4"}},
+		},
+		JetStream:    JetStreamVarz{Config: &JetStreamConfig{MaxStore: 2048, Domain: "hub"}, Stats: &JetStreamStats{}},
+		InMsgs:       25,
+		HTTPReqStats: map[string]uint64{"/varz": 1, "/connz": 2},
+		Tags:         []string{},
+	}
+
+	delta := func(d float64) *float64 { return &d }
+	expected := []Change{
+		{Path: "cluster.urls", Kind: ChangeModified, Old: []string{"a:6222", "b:6222"}, New: []string{"a:6222", "c:6222"}},
+		{Path: "gateway.gateways[C2]", Kind: ChangeRemoved, Old: RemoteGatewayOptsVarz{Name: "C2"}},
+		{Path: "gateway.gateways[C3].urls", Kind: ChangeModified, Old: []string{"c3:7222"}, New: []string{"c3:7223"}},
+		{Path: "gateway.gateways[C4]", Kind: ChangeAdded, New: RemoteGatewayOptsVarz{Name: "C4"}},
+		{Path: "http_req_stats./connz", Kind: ChangeAdded, New: uint64(2)},
+		{Path: "in_msgs", Kind: ChangeModified, Old: int64(10), New: int64(25), Delta: delta(15)},
+		{Path: "jetstream.config.domain", Kind: ChangeModified, Old: "", New: "hub"},
+		{Path: "jetstream.config.max_storage", Kind: ChangeModified, Old: int64(1024), New: int64(2048)},
+		{Path: "jetstream.stats", Kind: ChangeAdded, New: JetStreamStats{}},
+		{Path: "version", Kind: ChangeModified, Old: "2.9.14", New: "2.9.15"},
+	}
+
+	diff := DiffVarz(old, new)
+	if !reflect.DeepEqual(diff.Changes, expected) {
+		t.Fatalf("Invalid changes;\nwant: %+v;\ngot:  %+v", expected, diff.Changes)
+	}
+	if !diff.Changed() {
+		t.Fatalf("Expected diff to report changes")
+	}
+	if DiffVarz(old, old).Changed() {
+		t.Fatalf("Expected no changes when comparing the same response")
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteText(&buf); err != nil {
+		t.Fatalf("Unable to write diff: %s", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("~ in_msgs: 10 -> 25 (+15)\n")) || !bytes.Contains(buf.Bytes(), []byte("- gateway.gateways[C2]: ")) {
+		t.Fatalf("Invalid text output: %s", buf.String())
+	}
+}
+
+func TestDiffConnz(t *testing.T) {
+	old := &Connz{
+		NumConns: 2,
+		Conns: []*ConnInfo{
+			{Cid: 1, Name: "app", InMsgs: 5, Idle: "1s"},
+			{Cid: 2, Name: "worker"},
+		},
+	}
+	new := &Connz{
+		NumConns: 2,
+		Conns: []*ConnInfo{
+			{Cid: 1, Name: "app", InMsgs: 3, Idle: "2s", SubsDetail: []SubDetail{{Sid: "1", Cid: 1}, {Sid: "2", Cid: 1}}},
+			{Cid: 3, Name: "worker"},
+		},
+	}
+
+	expected := []Change{
+		{Path: "connections[1].in_msgs", Kind: ChangeModified, Old: int64(5), New: int64(3), Delta: func(d float64) *float64 { return &d }(-2)},
+		{Path: "connections[1].subscriptions_list_detail", Kind: ChangeModified, Old: []SubDetail(nil), New: []SubDetail{{Sid: "1", Cid: 1}, {Sid: "2", Cid: 1}}},
+		{Path: "connections[2]", Kind: ChangeRemoved, Old: ConnInfo{Cid: 2, Name: "worker"}},
+		{Path: "connections[3]", Kind: ChangeAdded, New: ConnInfo{Cid: 3, Name: "worker"}},
+	}
+	diff := DiffConnz(old, new)
+	if !reflect.DeepEqual(diff.Changes, expected) {
+		t.Fatalf("Invalid changes;\nwant: %+v;\ngot:  %+v", expected, diff.Changes)
+	}
+}
+
+func TestDiffJSInfo(t *testing.T) {
+	old := &JSInfo{
+		Config:         JetStreamConfig{MaxMemory: 1024},
+		JetStreamStats: JetStreamStats{API: JetStreamAPIStats{Total: 10, Errors: 1}},
+		AccountDetails: []*AccountDetail{{
+			Name: "ACC",
+			Streams: []StreamDetail{
+				{Name: "ORDERS", State: nats.StreamState{Msgs: 10, Bytes: 100}},
+				{Name: "EVENTS"},
+			},
+		}},
+	}
+	new := &JSInfo{
+		Config:         JetStreamConfig{MaxMemory: 1024},
+		JetStreamStats: JetStreamStats{API: JetStreamAPIStats{Total: 15, Errors: 1}},
+		AccountDetails: []*AccountDetail{{
+			Name: "ACC",
+			Streams: []StreamDetail{
+				{Name: "ORDERS", State: nats.StreamState{Msgs: 12, Bytes: 100}},
+			},
+		}},
+	}
+
+	expected := []Change{
+		{Path: "account_details[ACC].stream_detail[EVENTS]", Kind: ChangeRemoved, Old: StreamDetail{Name: "EVENTS"}},
+		{Path: "account_details[ACC].stream_detail[ORDERS].state.messages", Kind: ChangeModified, Old: uint64(10), New: uint64(12)},
+		{Path: "api.total", Kind: ChangeModified, Old: uint64(10), New: uint64(15), Delta: func(d float64) *float64 { return &d }(5)},
+	}
+	diff := DiffJSInfo(old, new)
+	if !reflect.DeepEqual(diff.Changes, expected) {
+		t.Fatalf("Invalid changes;\nwant: %+v;\ngot:  %+v", expected, diff.Changes)
+	}
+}
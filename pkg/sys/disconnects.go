@@ -0,0 +1,167 @@
+package sys
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	DefaultDisconnectBucket = time.Minute
+
+	// DefaultDisconnectLimit matches the number of closed connections retained by the server.
+	DefaultDisconnectLimit = 10000
+)
+
+type (
+	// DisconnectReport aggregates recently closed connections from all servers.
+	DisconnectReport struct {
+		Total     int                `json:"total"`
+		ByReason  []DisconnectGroup  `json:"by_reason,omitempty"`
+		ByAccount []DisconnectGroup  `json:"by_account,omitempty"`
+		ByClient  []DisconnectGroup  `json:"by_client,omitempty"`
+		Buckets   []DisconnectBucket `json:"buckets,omitempty"`
+	}
+
+	// DisconnectGroup is the number of closed connections sharing the same key,
+	// along with the time of the first and the last disconnect.
+	DisconnectGroup struct {
+		Key   string    `json:"key"`
+		Count int       `json:"count"`
+		First time.Time `json:"first"`
+		Last  time.Time `json:"last"`
+	}
+
+	// DisconnectBucket is the number of connections closed within a time bucket.
+	DisconnectBucket struct {
+		Start   time.Time      `json:"start"`
+		Count   int            `json:"count"`
+		Reasons map[string]int `json:"reasons"`
+	}
+
+	// DisconnectOptions are options passed to RecentDisconnects
+	DisconnectOptions struct {
+		// Since excludes connections closed before given time.
+		Since time.Time
+
+		// Bucket is the size of time buckets, DefaultDisconnectBucket by default.
+		Bucket time.Duration
+
+		// Account limits the report to connections from a single account.
+		Account string
+
+		// Limit is the maximum number of closed connections fetched from each server, DefaultDisconnectLimit by default.
+		Limit int
+	}
+)
+
+// RecentDisconnects fetches closed connections from all servers and groups them
+// by close reason, account and client (name and IP), as well as into time buckets.
+// Groups are sorted by the number of connections.
+func (a *Analyzer) RecentDisconnects(opts DisconnectOptions) (*DisconnectReport, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultDisconnectLimit
+	}
+	connz, err := a.client.ConnzPing(ConnzEventOptions{
+		ConnzOptions: ConnzOptions{
+			Sort:     ByStop,
+			State:    ConnClosed,
+			Username: true,
+			Account:  opts.Account,
+			Limit:    limit,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recentDisconnects(opts, connz), nil
+}
+
+func recentDisconnects(opts DisconnectOptions, connz []ConnzResp) *DisconnectReport {
+	bucketSize := opts.Bucket
+	if bucketSize <= 0 {
+		bucketSize = DefaultDisconnectBucket
+	}
+
+	report := &DisconnectReport{}
+	byReason := make(map[string]*DisconnectGroup)
+	byAccount := make(map[string]*DisconnectGroup)
+	byClient := make(map[string]*DisconnectGroup)
+	buckets := make(map[time.Time]*DisconnectBucket)
+
+	for _, resp := range connz {
+		for _, conn := range resp.Connz.Conns {
+			if conn == nil {
+				continue
+			}
+			stop := conn.LastActivity
+			if conn.Stop != nil {
+				stop = *conn.Stop
+			}
+			if stop.Before(opts.Since) {
+				continue
+			}
+			report.Total++
+			addDisconnect(byReason, conn.Reason, stop)
+			addDisconnect(byAccount, conn.Account, stop)
+			addDisconnect(byClient, disconnectClientKey(conn), stop)
+
+			start := stop.Truncate(bucketSize)
+			bucket, ok := buckets[start]
+			if !ok {
+				bucket = &DisconnectBucket{Start: start, Reasons: make(map[string]int)}
+				buckets[start] = bucket
+			}
+			bucket.Count++
+			bucket.Reasons[conn.Reason]++
+		}
+	}
+
+	report.ByReason = sortedDisconnectGroups(byReason)
+	report.ByAccount = sortedDisconnectGroups(byAccount)
+	report.ByClient = sortedDisconnectGroups(byClient)
+	for _, bucket := range buckets {
+		report.Buckets = append(report.Buckets, *bucket)
+	}
+	sort.Slice(report.Buckets, func(i, j int) bool {
+		return report.Buckets[i].Start.Before(report.Buckets[j].Start)
+	})
+	return report
+}
+
+// disconnectClientKey identifies a client by connection name and IP.
+func disconnectClientKey(conn *ConnInfo) string {
+	if conn.Name == "" {
+		return conn.IP
+	}
+	return conn.Name + "@" + conn.IP
+}
+
+func addDisconnect(groups map[string]*DisconnectGroup, key string, stop time.Time) {
+	group, ok := groups[key]
+	if !ok {
+		groups[key] = &DisconnectGroup{Key: key, Count: 1, First: stop, Last: stop}
+		return
+	}
+	group.Count++
+	if stop.Before(group.First) {
+		group.First = stop
+	}
+	if stop.After(group.Last) {
+		group.Last = stop
+	}
+}
+
+func sortedDisconnectGroups(groups map[string]*DisconnectGroup) []DisconnectGroup {
+	res := make([]DisconnectGroup, 0, len(groups))
+	for _, group := range groups {
+		res = append(res, *group)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
@@ -0,0 +1,115 @@
+package sys
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestRecentDisconnects(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		nc, err := nats.Connect(c.servers[i].ClientURL(), nats.Name("flapping"))
+		if err != nil {
+			t.Fatalf("Error establishing connection: %s", err)
+		}
+		nc.Close()
+	}
+	if _, err := nats.Connect(c.servers[0].ClientURL(), nats.UserInfo("pp", "invalid"), nats.Name("bad-auth")); err == nil {
+		t.Fatalf("Expected authorization error")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	report, err := sys.RecentDisconnects(DisconnectOptions{Since: start})
+	if err != nil {
+		t.Fatalf("Unable to fetch disconnects: %s", err)
+	}
+	// failed connection may be retried using other server addresses, so only check the closed ones
+	reasons := make(map[string]int)
+	for _, group := range report.ByReason {
+		reasons[group.Key] = group.Count
+	}
+	if reasons["Client Closed"] != 3 || reasons["Authentication Failure"] == 0 {
+		t.Fatalf("Invalid reason groups: %+v", report.ByReason)
+	}
+	var found bool
+	for _, group := range report.ByClient {
+		if group.Key == "flapping@127.0.0.1" {
+			found = group.Count == 3
+		}
+	}
+	if !found {
+		t.Fatalf("Invalid client groups: %+v", report.ByClient)
+	}
+
+	report, err = sys.RecentDisconnects(DisconnectOptions{Since: time.Now()})
+	if err != nil {
+		t.Fatalf("Unable to fetch disconnects: %s", err)
+	}
+	if report.Total != 0 {
+		t.Fatalf("Invalid number of disconnects: %d; want: %d", report.Total, 0)
+	}
+}
+
+func TestRecentDisconnectsBuckets(t *testing.T) {
+	base := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	closed := func(cid uint64, offset time.Duration, reason, account string) *ConnInfo {
+		stop := base.Add(offset)
+		return &ConnInfo{Cid: cid, Stop: &stop, Reason: reason, Account: account, IP: "10.0.0.1"}
+	}
+	connz := []ConnzResp{
+		{Connz: Connz{Conns: []*ConnInfo{
+			closed(1, 10*time.Second, "Client Closed", "A"),
+			closed(2, 70*time.Second, "Slow Consumer (Write Deadline)", "A"),
+		}}},
+		{Connz: Connz{Conns: []*ConnInfo{
+			closed(1, 80*time.Second, "Slow Consumer (Write Deadline)", "B"),
+			closed(2, -time.Hour, "Client Closed", "B"),
+		}}},
+	}
+
+	report := recentDisconnects(DisconnectOptions{Since: base, Bucket: time.Minute}, connz)
+	if report.Total != 3 {
+		t.Fatalf("Invalid number of disconnects: %d; want: %d", report.Total, 3)
+	}
+	if len(report.Buckets) != 2 {
+		t.Fatalf("Invalid number of buckets: %+v", report.Buckets)
+	}
+	if !report.Buckets[0].Start.Equal(base) || report.Buckets[0].Count != 1 {
+		t.Fatalf("Invalid first bucket: %+v", report.Buckets[0])
+	}
+	if report.Buckets[1].Count != 2 || report.Buckets[1].Reasons["Slow Consumer (Write Deadline)"] != 2 {
+		t.Fatalf("Invalid second bucket: %+v", report.Buckets[1])
+	}
+	slow := report.ByReason[0]
+	if slow.Key != "Slow Consumer (Write Deadline)" || slow.Count != 2 || !slow.First.Equal(base.Add(70*time.Second)) || !slow.Last.Equal(base.Add(80*time.Second)) {
+		t.Fatalf("Invalid reason group: %+v", slow)
+	}
+	if len(report.ByAccount) != 2 || report.ByAccount[0].Key != "A" || report.ByAccount[0].Count != 2 {
+		t.Fatalf("Invalid account groups: %+v", report.ByAccount)
+	}
+}
@@ -0,0 +1,152 @@
+package sys
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+type (
+	// DriftReport groups servers by version and configuration settings reported in VARZ.
+	DriftReport struct {
+		Servers  int            `json:"servers"`
+		Drifted  bool           `json:"drifted"`
+		Settings []SettingDrift `json:"settings"`
+	}
+
+	// SettingDrift lists distinct values of a single setting across servers.
+	// Values are sorted by the number of servers, so the first value is the one used by most servers.
+	// Servers not using the most common value are listed as outliers.
+	SettingDrift struct {
+		Setting  string         `json:"setting"`
+		Drifted  bool           `json:"drifted"`
+		Values   []SettingValue `json:"values"`
+		Outliers []string       `json:"outliers,omitempty"`
+	}
+
+	// SettingValue is a setting value along with the names of servers using it.
+	SettingValue struct {
+		Value   string   `json:"value"`
+		Servers []string `json:"servers"`
+	}
+
+	// DriftOptions are options passed to DriftReport
+	DriftOptions struct {
+		// IncludeJetStreamLimits compares JetStream memory and storage limits.
+		// Unless configured explicitly, the limits are computed from resources available on the host,
+		// so they differ between servers of a default cluster and are not compared by default.
+		IncludeJetStreamLimits bool
+
+		// Ignore lists names of settings which are not compared, e.g. "git_commit".
+		Ignore []string
+	}
+
+	driftSetting struct {
+		name    string
+		value   func(*Varz) string
+		dynamic bool
+	}
+)
+
+const jsDisabledValue = "disabled"
+
+var driftSettings = []driftSetting{
+	{"version", func(v *Varz) string { return v.Version }, false},
+	{"git_commit", func(v *Varz) string { return v.GitCommit }, false},
+	{"go", func(v *Varz) string { return v.GoVersion }, false},
+	{"max_payload", func(v *Varz) string { return strconv.Itoa(v.MaxPayload) }, false},
+	{"max_connections", func(v *Varz) string { return strconv.Itoa(v.MaxConn) }, false},
+	{"ping_interval", func(v *Varz) string { return v.PingInterval.String() }, false},
+	{"write_deadline", func(v *Varz) string { return v.WriteDeadline.String() }, false},
+	{"tls_required", func(v *Varz) string { return strconv.FormatBool(v.TLSRequired) }, false},
+	{"tls_verify", func(v *Varz) string { return strconv.FormatBool(v.TLSVerify) }, false},
+	{"jetstream.max_memory", jsConfigValue(func(c *JetStreamConfig) string { return strconv.FormatInt(c.MaxMemory, 10) }), true},
+	{"jetstream.max_storage", jsConfigValue(func(c *JetStreamConfig) string { return strconv.FormatInt(c.MaxStore, 10) }), true},
+	{"jetstream.domain", jsConfigValue(func(c *JetStreamConfig) string { return c.Domain }), false},
+	{"jetstream.compress_ok", jsConfigValue(func(c *JetStreamConfig) string { return strconv.FormatBool(c.CompressOK) }), false},
+	{"jetstream.unique_tag", jsConfigValue(func(c *JetStreamConfig) string { return c.UniqueTag }), false},
+}
+
+func jsConfigValue(value func(*JetStreamConfig) string) func(*Varz) string {
+	return func(v *Varz) string {
+		if v.JetStream.Config == nil {
+			return jsDisabledValue
+		}
+		return value(v.JetStream.Config)
+	}
+}
+
+// DriftReport compares versions and configuration of all servers and reports settings
+// for which servers use different values.
+// JetStream store directory is not compared, as it is specific to the host.
+func (a *Analyzer) DriftReport(opts DriftOptions) (*DriftReport, error) {
+	varz, err := a.client.VarzPing(VarzEventOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return driftReport(opts, varz), nil
+}
+
+func driftReport(opts DriftOptions, varz []VarzResp) *DriftReport {
+	ignored := make(map[string]struct{}, len(opts.Ignore))
+	for _, name := range opts.Ignore {
+		ignored[name] = struct{}{}
+	}
+	report := &DriftReport{
+		Servers:  len(varz),
+		Settings: make([]SettingDrift, 0, len(driftSettings)),
+	}
+	for _, setting := range driftSettings {
+		if _, ok := ignored[setting.name]; ok || (setting.dynamic && !opts.IncludeJetStreamLimits) {
+			continue
+		}
+		servers := make(map[string][]string)
+		for i := range varz {
+			value := setting.value(&varz[i].Varz)
+			servers[value] = append(servers[value], serverName(varz[i].Server))
+		}
+
+		drift := SettingDrift{
+			Setting: setting.name,
+			Values:  make([]SettingValue, 0, len(servers)),
+		}
+		for value, names := range servers {
+			sort.Strings(names)
+			drift.Values = append(drift.Values, SettingValue{Value: value, Servers: names})
+		}
+		sort.Slice(drift.Values, func(i, j int) bool {
+			if len(drift.Values[i].Servers) != len(drift.Values[j].Servers) {
+				return len(drift.Values[i].Servers) > len(drift.Values[j].Servers)
+			}
+			return drift.Values[i].Value < drift.Values[j].Value
+		})
+		if len(drift.Values) > 1 {
+			drift.Drifted = true
+			report.Drifted = true
+			for _, value := range drift.Values[1:] {
+				drift.Outliers = append(drift.Outliers, value.Servers...)
+			}
+			sort.Strings(drift.Outliers)
+		}
+		report.Settings = append(report.Settings, drift)
+	}
+	return report
+}
+
+// Setting returns drift information for a setting with given name.
+func (r *DriftReport) Setting(name string) (SettingDrift, error) {
+	for _, setting := range r.Settings {
+		if setting.Setting == name {
+			return setting, nil
+		}
+	}
+	return SettingDrift{}, fmt.Errorf("%w: unknown setting %q", ErrValidation, name)
+}
+
+// serverName returns server name or, if not set, server ID.
+func serverName(info ServerInfo) string {
+	if info.Name != "" {
+		return info.Name
+	}
+	return info.ID
+}
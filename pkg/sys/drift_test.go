@@ -0,0 +1,121 @@
+package sys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestDriftReport(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	report, err := sys.DriftReport(DriftOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch drift report: %s", err)
+	}
+	if report.Servers != 3 {
+		t.Fatalf("Invalid number of servers: %d; want: %d", report.Servers, 3)
+	}
+	if report.Drifted {
+		t.Fatalf("Expected no drift: %+v", report)
+	}
+	for _, name := range []string{"version", "max_payload", "max_connections", "ping_interval"} {
+		setting, err := report.Setting(name)
+		if err != nil {
+			t.Fatalf("Unable to get %q setting: %s", name, err)
+		}
+		if setting.Drifted || len(setting.Values) != 1 || len(setting.Values[0].Servers) != 3 {
+			t.Fatalf("Expected no drift for %q: %+v", name, setting)
+		}
+	}
+	// dynamic JetStream limits are not compared by default
+	if _, err := report.Setting("jetstream.max_storage"); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected error; want: %s; got: %s", ErrValidation, err)
+	}
+	if _, err := report.Setting("asd"); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected error; want: %s; got: %s", ErrValidation, err)
+	}
+
+	report, err = sys.DriftReport(DriftOptions{IncludeJetStreamLimits: true, Ignore: []string{"version"}})
+	if err != nil {
+		t.Fatalf("Unable to fetch drift report: %s", err)
+	}
+	if _, err := report.Setting("jetstream.max_storage"); err != nil {
+		t.Fatalf("Unable to get JetStream storage limit: %s", err)
+	}
+	if _, err := report.Setting("version"); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected ignored setting not to be reported; got: %v", err)
+	}
+}
+
+func TestDriftReportOutliers(t *testing.T) {
+	varz := func(name, version string, maxPayload int, js *JetStreamConfig) VarzResp {
+		return VarzResp{
+			Server: ServerInfo{Name: name},
+			Varz: Varz{
+				Version:      version,
+				MaxPayload:   maxPayload,
+				PingInterval: 2 * time.Minute,
+				JetStream:    JetStreamVarz{Config: js},
+			},
+		}
+	}
+	js := &JetStreamConfig{MaxMemory: 1024, MaxStore: 2048, StoreDir: "/data/s1"}
+	report := driftReport(DriftOptions{IncludeJetStreamLimits: true}, []VarzResp{
+		varz("s1", "2.9.15", 1024, js),
+		varz("s2", "2.9.14", 1024, &JetStreamConfig{MaxMemory: 1024, MaxStore: 2048, StoreDir: "/data/s2"}),
+		varz("s3", "2.9.15", 2048, nil),
+	})
+
+	if !report.Drifted {
+		t.Fatalf("Expected drift to be reported")
+	}
+	tests := []struct {
+		setting  string
+		drifted  bool
+		value    string
+		outliers []string
+	}{
+		{setting: "version", drifted: true, value: "2.9.15", outliers: []string{"s2"}},
+		{setting: "max_payload", drifted: true, value: "1024", outliers: []string{"s3"}},
+		{setting: "ping_interval", value: "2m0s"},
+		{setting: "jetstream.max_memory", drifted: true, value: "1024", outliers: []string{"s3"}},
+	}
+	for _, test := range tests {
+		t.Run(test.setting, func(t *testing.T) {
+			setting, err := report.Setting(test.setting)
+			if err != nil {
+				t.Fatalf("Unable to get setting: %s", err)
+			}
+			if setting.Drifted != test.drifted || setting.Values[0].Value != test.value {
+				t.Fatalf("Invalid setting drift: %+v", setting)
+			}
+			if strings.Join(setting.Outliers, ",") != strings.Join(test.outliers, ",") {
+				t.Fatalf("Invalid outliers; want: %v; got: %v", test.outliers, setting.Outliers)
+			}
+		})
+	}
+}
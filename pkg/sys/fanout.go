@@ -0,0 +1,185 @@
+package sys
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultFanOutConcurrency = 8
+
+type (
+	// FanOutOptions are options passed to fan-out requests, e.g. VarzFanOut.
+	FanOutOptions struct {
+		// Servers is the list of server IDs to query.
+		// If empty, servers matching Selector are discovered, see SelectServers.
+		Servers []string
+
+		// Selector selects servers to query if Servers is empty.
+		Selector Selector
+
+		// Concurrency is the maximum number of requests in flight, DefaultFanOutConcurrency by default.
+		Concurrency int
+
+		// Timeout is the timeout of each request, client request timeout by default.
+		Timeout time.Duration
+	}
+
+	// FanOutError is returned by fan-out requests if requests to some of the servers failed.
+	FanOutError struct {
+		Errors []ServerError
+	}
+
+	// ServerError is an error of a request to a single server.
+	ServerError struct {
+		ServerID string
+		Err      error
+	}
+)
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server %s: %s", e.ServerID, e.Err)
+}
+
+func (e *ServerError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FanOutError) Error() string {
+	errs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err.Error())
+	}
+	return fmt.Sprintf("requests to %d servers failed: %s", len(e.Errors), strings.Join(errs, "; "))
+}
+
+// Unwrap returns errors of all failed requests.
+func (e *FanOutError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for i := range e.Errors {
+		errs = append(errs, &e.Errors[i])
+	}
+	return errs
+}
+
+// fanOut sends the request to each server, with at most opts.Concurrency requests in flight.
+// Responses are returned in the order of servers, along with *FanOutError if any of the requests failed.
+func fanOut[T any](s *System, opts FanOutOptions, request func(s *System, id string) (*T, error)) ([]T, error) {
+	if opts.Concurrency < 0 {
+		return nil, fmt.Errorf("%w: concurrency cannot be negative", ErrValidation)
+	}
+	if opts.Timeout < 0 {
+		return nil, fmt.Errorf("%w: timeout cannot be negative", ErrValidation)
+	}
+	concurrency := opts.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultFanOutConcurrency
+	}
+
+	servers := opts.Servers
+	if len(servers) == 0 {
+		selected, err := s.SelectServers(opts.Selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, server := range selected {
+			servers = append(servers, server.ID)
+		}
+	}
+
+	client := s
+	if opts.Timeout > 0 {
+		clientOpts := *s.opts
+		clientOpts.timeout = opts.Timeout
+		withTimeout := *s
+		withTimeout.opts = &clientOpts
+		client = &withTimeout
+	}
+
+	responses := make([]*T, len(servers))
+	errs := make([]error, len(servers))
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for i, id := range servers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			responses[i], errs[i] = request(client, id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	res := make([]T, 0, len(servers))
+	var fanOutErr *FanOutError
+	for i, resp := range responses {
+		if errs[i] != nil {
+			if fanOutErr == nil {
+				fanOutErr = &FanOutError{}
+			}
+			if errors.Is(errs[i], ErrInvalidServerID) {
+				s.InvalidateServerCache()
+			}
+			fanOutErr.Errors = append(fanOutErr.Errors, ServerError{ServerID: servers[i], Err: errs[i]})
+			continue
+		}
+		res = append(res, *resp)
+	}
+	if fanOutErr != nil {
+		return res, fanOutErr
+	}
+	return res, nil
+}
+
+// VarzFanOut requests general server information from each server separately.
+// Responses from servers which responded are returned even if some of the requests failed.
+func (s *System) VarzFanOut(opts FanOutOptions, varzOpts VarzEventOptions) ([]VarzResp, error) {
+	return fanOut(s, opts, func(s *System, id string) (*VarzResp, error) {
+		return s.Varz(id, varzOpts)
+	})
+}
+
+// ConnzFanOut requests connection information from each server separately.
+// Responses from servers which responded are returned even if some of the requests failed.
+func (s *System) ConnzFanOut(opts FanOutOptions, connzOpts ConnzEventOptions) ([]ConnzResp, error) {
+	return fanOut(s, opts, func(s *System, id string) (*ConnzResp, error) {
+		return s.Connz(id, connzOpts)
+	})
+}
+
+// ServerSubszFanOut requests subscription information from each server separately.
+// Responses from servers which responded are returned even if some of the requests failed.
+func (s *System) ServerSubszFanOut(opts FanOutOptions, subszOpts SubszOptions) ([]SubszResp, error) {
+	return fanOut(s, opts, func(s *System, id string) (*SubszResp, error) {
+		return s.ServerSubsz(id, subszOpts)
+	})
+}
+
+// JszFanOut requests JetStream information from each server separately.
+// Responses from servers which responded are returned even if some of the requests failed.
+func (s *System) JszFanOut(opts FanOutOptions, jszOpts JszEventOptions) ([]JSZResp, error) {
+	return fanOut(s, opts, func(s *System, id string) (*JSZResp, error) {
+		return s.Jsz(id, jszOpts)
+	})
+}
+
+// ServerStatszFanOut requests server statistics from each server separately.
+// Responses from servers which responded are returned even if some of the requests failed.
+func (s *System) ServerStatszFanOut(opts FanOutOptions, statszOpts StatszEventOptions) ([]ServerStatszResp, error) {
+	return fanOut(s, opts, func(s *System, id string) (*ServerStatszResp, error) {
+		return s.ServerStatsz(id, statszOpts)
+	})
+}
+
+// HealthzFanOut checks health status of each server separately.
+// Responses from servers which responded are returned even if some of the requests failed.
+func (s *System) HealthzFanOut(opts FanOutOptions, healthzOpts HealthzOptions) ([]HealthzResp, error) {
+	return fanOut(s, opts, func(s *System, id string) (*HealthzResp, error) {
+		return s.Healthz(id, healthzOpts)
+	})
+}
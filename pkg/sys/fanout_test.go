@@ -0,0 +1,155 @@
+package sys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestFanOut(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	t.Run("discovered servers", func(t *testing.T) {
+		jsz, err := sys.JszFanOut(FanOutOptions{Concurrency: 1}, JszEventOptions{JszOptions: JszOptions{Streams: true}})
+		if err != nil {
+			t.Fatalf("Unable to fetch JSZ: %s", err)
+		}
+		if len(jsz) != 3 {
+			t.Fatalf("Invalid number of responses: %d; want: %d", len(jsz), 3)
+		}
+		for i, name := range []string{"s1", "s2", "s3"} {
+			if jsz[i].Server.Name != name {
+				t.Fatalf("Invalid server; want: %s; got: %s", name, jsz[i].Server.Name)
+			}
+		}
+	})
+
+	t.Run("per-server errors", func(t *testing.T) {
+		servers := []string{c.servers[0].ID(), "unknown", c.servers[2].ID()}
+		varz, err := sys.VarzFanOut(FanOutOptions{Servers: servers}, VarzEventOptions{})
+		var fanOutErr *FanOutError
+		if !errors.As(err, &fanOutErr) {
+			t.Fatalf("Expected fan-out error; got: %v", err)
+		}
+		if len(fanOutErr.Errors) != 1 || fanOutErr.Errors[0].ServerID != "unknown" {
+			t.Fatalf("Invalid errors: %+v", fanOutErr.Errors)
+		}
+		if !errors.Is(fanOutErr.Errors[0].Err, ErrInvalidServerID) {
+			t.Fatalf("Expected error: %s; got: %s", ErrInvalidServerID, fanOutErr.Errors[0].Err)
+		}
+		if len(varz) != 2 || varz[0].Server.ID != servers[0] || varz[1].Server.ID != servers[2] {
+			t.Fatalf("Invalid responses: %+v", varz)
+		}
+	})
+
+	t.Run("selector", func(t *testing.T) {
+		healthz, err := sys.HealthzFanOut(FanOutOptions{Selector: Selector{Name: "s2"}}, HealthzOptions{})
+		if err != nil {
+			t.Fatalf("Unable to fetch HEALTHZ: %s", err)
+		}
+		if len(healthz) != 1 || healthz[0].Server.Name != "s2" {
+			t.Fatalf("Invalid responses: %+v", healthz)
+		}
+		if _, err := sys.HealthzFanOut(FanOutOptions{Selector: Selector{Cluster: "C2"}}, HealthzOptions{}); !errors.Is(err, ErrNoMatchingServers) {
+			t.Fatalf("Expected error: %s; got: %v", ErrNoMatchingServers, err)
+		}
+	})
+
+	t.Run("stale server cache", func(t *testing.T) {
+		if _, err := sys.SelectServers(Selector{}); err != nil {
+			t.Fatalf("Unable to select servers: %s", err)
+		}
+		sys.resolver.Lock()
+		sys.resolver.servers = append(sys.resolver.servers, ServerInfo{Name: "s4", ID: "unknown"})
+		sys.resolver.Unlock()
+		_, err := sys.VarzFanOut(FanOutOptions{Selector: Selector{Name: "s4"}}, VarzEventOptions{})
+		var fanOutErr *FanOutError
+		if !errors.As(err, &fanOutErr) || !errors.Is(fanOutErr.Errors[0].Err, ErrInvalidServerID) {
+			t.Fatalf("Expected error: %s; got: %v", ErrInvalidServerID, err)
+		}
+		if _, err := sys.SelectServers(Selector{Name: "s4"}); !errors.Is(err, ErrNoMatchingServers) {
+			t.Fatalf("Expected error: %s; got: %v", ErrNoMatchingServers, err)
+		}
+	})
+}
+
+func TestFanOutConcurrency(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	sysConn, err := nats.Connect(c.servers[0].ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	// simulated slow servers, tracking the number of requests in flight
+	var inFlight, maxInFlight int32
+	servers := make([]string, 0)
+	for i := 0; i < 6; i++ {
+		id := fmt.Sprintf("slow%d", i)
+		servers = append(servers, id)
+		_, err := sysConn.Subscribe(fmt.Sprintf(srvStatszSubj, id), func(msg *nats.Msg) {
+			go func() {
+				n := atomic.AddInt32(&inFlight, 1)
+				for {
+					prev := atomic.LoadInt32(&maxInFlight)
+					if n <= prev || atomic.CompareAndSwapInt32(&maxInFlight, prev, n) {
+						break
+					}
+				}
+				time.Sleep(50 * time.Millisecond)
+				atomic.AddInt32(&inFlight, -1)
+				data, _ := json.Marshal(ServerStatszResp{Server: ServerInfo{ID: id}})
+				msg.Respond(data)
+			}()
+		})
+		if err != nil {
+			t.Fatalf("Error subscribing: %s", err)
+		}
+	}
+	sysConn.Flush()
+
+	sys, err := NewSysClient(sysConn)
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	statsz, err := sys.ServerStatszFanOut(FanOutOptions{Servers: servers, Concurrency: 2}, StatszEventOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch STATSZ: %s", err)
+	}
+	if len(statsz) != 6 {
+		t.Fatalf("Invalid number of responses: %d; want: %d", len(statsz), 6)
+	}
+	if maxInFlight != 2 {
+		t.Fatalf("Invalid number of concurrent requests: %d; want: %d", maxInFlight, 2)
+	}
+
+	// per-request timeout
+	_, err = sys.ServerStatszFanOut(FanOutOptions{Servers: servers[:1], Timeout: 10 * time.Millisecond}, StatszEventOptions{})
+	if !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("Expected error: %s; got: %v", nats.ErrTimeout, err)
+	}
+}
@@ -80,13 +80,12 @@ func (s *System) Healthz(id string, opts HealthzOptions) (*HealthzResp, error) {
 	if id == "" {
 		return nil, fmt.Errorf("%w: server id cannot be empty", ErrValidation)
 	}
-	conn := s.nc
 	subj := fmt.Sprintf(srvHealthzSubj, id)
 	payload, err := json.Marshal(opts)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := conn.Request(subj, payload, s.opts.timeout)
+	resp, err := s.request(subj, payload)
 	if err != nil {
 		if errors.Is(err, nats.ErrNoResponders) {
 			return nil, fmt.Errorf("%w: %s", ErrInvalidServerID, id)
@@ -0,0 +1,476 @@
+package sys
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHistoryCapacity      = 3600
+	DefaultHistoryPollInterval  = 10 * time.Second
+	DefaultHistoryFlushInterval = time.Minute
+)
+
+type (
+	// HistoryStore is a bounded in-memory store of metric samples.
+	// Each series (a metric of a single server) is kept in a ring buffer,
+	// so the oldest samples are dropped once the capacity is reached.
+	// Metric names are the same as returned by MetricNames.
+	HistoryStore struct {
+		mu       sync.RWMutex
+		capacity int
+		file     string
+		series   map[seriesKey]*ring
+		names    map[string]string
+	}
+
+	HistoryOpt func(*HistoryStore) error
+
+	// HistoryQuery selects samples returned by HistoryStore.Query.
+	HistoryQuery struct {
+		// Server is the ID or name of the server. If empty, all servers are included.
+		Server string
+
+		// Metric is the name of the metric. If empty, all metrics are included.
+		Metric string
+
+		// From and To limit the time range (inclusive). Zero values are unbounded.
+		From time.Time
+		To   time.Time
+
+		// Step downsamples samples into buckets of given size. If 0, samples are returned as stored.
+		Step time.Duration
+
+		// Aggregation is used to combine samples within a bucket, AggregateAvg by default.
+		Aggregation Aggregation
+	}
+
+	// HistoryResult contains series matching a query, sorted by server name and metric.
+	HistoryResult struct {
+		Series []Series `json:"series"`
+	}
+
+	// Series contains samples of a metric of a single server, sorted by time.
+	Series struct {
+		Server     string  `json:"server"`
+		ServerName string  `json:"server_name,omitempty"`
+		Metric     string  `json:"metric"`
+		Points     []Point `json:"points"`
+	}
+
+	Point struct {
+		Time  time.Time `json:"time"`
+		Value float64   `json:"value"`
+	}
+
+	Aggregation string
+
+	// HistoryPollOptions are options passed to PollHistory
+	HistoryPollOptions struct {
+		// Interval is the time between polls, DefaultHistoryPollInterval by default.
+		Interval time.Duration
+
+		// Endpoints are polled endpoints. VARZ, STATSZ and JSZ are supported; all of them are polled by default.
+		Endpoints []Endpoint
+
+		// FlushInterval is the minimum time between flushes of a file-backed store, DefaultHistoryFlushInterval by default.
+		FlushInterval time.Duration
+	}
+
+	seriesKey struct {
+		server string
+		metric string
+	}
+
+	// ring is a buffer of points which grows up to its capacity, after which the oldest points are overwritten.
+	ring struct {
+		points   []Point
+		capacity int
+		start    int
+	}
+)
+
+// Possible aggregations
+const (
+	AggregateAvg  Aggregation = "avg"
+	AggregateMin  Aggregation = "min"
+	AggregateMax  Aggregation = "max"
+	AggregateLast Aggregation = "last"
+)
+
+// HistoryCapacity sets the maximum number of samples kept for each series, DefaultHistoryCapacity by default.
+func HistoryCapacity(capacity int) HistoryOpt {
+	return func(h *HistoryStore) error {
+		if capacity <= 0 {
+			return fmt.Errorf("%w: capacity has to be greater than 0", ErrValidation)
+		}
+		h.capacity = capacity
+		return nil
+	}
+}
+
+// HistoryFile makes the store persistent. Samples are loaded from the file when the store is created
+// (if the file exists) and written to the file on Flush.
+func HistoryFile(path string) HistoryOpt {
+	return func(h *HistoryStore) error {
+		if path == "" {
+			return fmt.Errorf("%w: file path cannot be empty", ErrValidation)
+		}
+		h.file = path
+		return nil
+	}
+}
+
+func NewHistoryStore(opts ...HistoryOpt) (*HistoryStore, error) {
+	h := &HistoryStore{
+		capacity: DefaultHistoryCapacity,
+		series:   make(map[seriesKey]*ring),
+		names:    make(map[string]string),
+	}
+	for _, opt := range opts {
+		if err := opt(h); err != nil {
+			return nil, err
+		}
+	}
+	if h.file != "" {
+		if err := h.load(); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// Add stores metric samples of a server taken at given time.
+func (h *HistoryStore) Add(server ServerInfo, t time.Time, metrics map[string]float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if server.Name != "" {
+		h.names[server.ID] = server.Name
+	}
+	for metric, value := range metrics {
+		h.add(seriesKey{server: server.ID, metric: metric}, Point{Time: t, Value: value})
+	}
+}
+
+func (h *HistoryStore) add(key seriesKey, point Point) {
+	r, ok := h.series[key]
+	if !ok {
+		r = &ring{capacity: h.capacity}
+		h.series[key] = r
+	}
+	r.push(point)
+}
+
+// AddVarz stores metrics from VARZ responses.
+func (h *HistoryStore) AddVarz(t time.Time, resps []VarzResp) {
+	for i := range resps {
+		h.Add(resps[i].Server, t, VarzMetrics(&resps[i]))
+	}
+}
+
+// AddStatsz stores metrics from STATSZ responses.
+func (h *HistoryStore) AddStatsz(t time.Time, resps []ServerStatszResp) {
+	for i := range resps {
+		h.Add(resps[i].Server, t, StatszMetrics(&resps[i]))
+	}
+}
+
+// AddJsz stores metrics from JSZ responses.
+func (h *HistoryStore) AddJsz(t time.Time, resps []JSZResp) {
+	for i := range resps {
+		h.Add(resps[i].Server, t, JszMetrics(&resps[i]))
+	}
+}
+
+// Query returns series matching the query.
+func (h *HistoryStore) Query(q HistoryQuery) (*HistoryResult, error) {
+	if q.Step < 0 {
+		return nil, fmt.Errorf("%w: step cannot be negative", ErrValidation)
+	}
+	aggregate, err := aggregation(q.Aggregation)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	res := &HistoryResult{Series: make([]Series, 0)}
+	for key, r := range h.series {
+		name := h.names[key.server]
+		if q.Server != "" && q.Server != key.server && q.Server != name {
+			continue
+		}
+		if q.Metric != "" && q.Metric != key.metric {
+			continue
+		}
+		points := make([]Point, 0)
+		r.each(func(p Point) {
+			if (q.From.IsZero() || !p.Time.Before(q.From)) && (q.To.IsZero() || !p.Time.After(q.To)) {
+				points = append(points, p)
+			}
+		})
+		if len(points) == 0 {
+			continue
+		}
+		if q.Step > 0 {
+			points = downsample(points, q.Step, aggregate)
+		}
+		res.Series = append(res.Series, Series{
+			Server:     key.server,
+			ServerName: name,
+			Metric:     key.metric,
+			Points:     points,
+		})
+	}
+	sort.Slice(res.Series, func(i, j int) bool {
+		if res.Series[i].ServerName != res.Series[j].ServerName {
+			return res.Series[i].ServerName < res.Series[j].ServerName
+		}
+		if res.Series[i].Server != res.Series[j].Server {
+			return res.Series[i].Server < res.Series[j].Server
+		}
+		return res.Series[i].Metric < res.Series[j].Metric
+	})
+	return res, nil
+}
+
+// Flush writes all samples to the file set with HistoryFile.
+// The file is replaced atomically.
+func (h *HistoryStore) Flush() error {
+	if h.file == "" {
+		return nil
+	}
+	res, err := h.Query(HistoryQuery{})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(h.file), filepath.Base(h.file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := json.NewEncoder(tmp).Encode(res); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), h.file)
+}
+
+func (h *HistoryStore) load() error {
+	f, err := os.Open(h.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	var res HistoryResult
+	if err := json.NewDecoder(f).Decode(&res); err != nil {
+		return fmt.Errorf("reading history file: %w", err)
+	}
+	for _, series := range res.Series {
+		if series.ServerName != "" {
+			h.names[series.Server] = series.ServerName
+		}
+		for _, point := range series.Points {
+			h.add(seriesKey{server: series.Server, metric: series.Metric}, point)
+		}
+	}
+	return nil
+}
+
+// PollHistory polls the endpoints and stores the metrics until the context is done.
+// If the store is file-backed, it is flushed after a poll once FlushInterval passed since the previous flush.
+// Samples polled since the last flush are not written when polling stops; call Flush after the returned channel is closed.
+// Errors are sent on the returned channel, which is closed when the context is done.
+func PollHistory(ctx context.Context, client SysClient, store *HistoryStore, opts HistoryPollOptions) <-chan error {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultHistoryPollInterval
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultHistoryFlushInterval
+	}
+	if len(opts.Endpoints) == 0 {
+		opts.Endpoints = []Endpoint{EndpointVarz, EndpointStatsz, EndpointJsz}
+	}
+	errs := make(chan error)
+	go func() {
+		defer close(errs)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		flushedAt := time.Now()
+		for {
+			pollErrs := pollHistory(client, store, opts.Endpoints)
+			if time.Since(flushedAt) >= opts.FlushInterval {
+				if err := store.Flush(); err != nil {
+					pollErrs = append(pollErrs, fmt.Errorf("flushing history: %w", err))
+				}
+				flushedAt = time.Now()
+			}
+			for _, err := range pollErrs {
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return errs
+}
+
+func pollHistory(client SysClient, store *HistoryStore, endpoints []Endpoint) []error {
+	var errs []error
+	now := time.Now().UTC()
+	for _, endpoint := range endpoints {
+		switch endpoint {
+		case EndpointVarz:
+			resp, err := client.VarzPing(VarzEventOptions{})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("polling VARZ: %w", err))
+				continue
+			}
+			store.AddVarz(now, resp)
+		case EndpointStatsz:
+			resp, err := client.ServerStatszPing(StatszEventOptions{})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("polling STATSZ: %w", err))
+				continue
+			}
+			store.AddStatsz(now, resp)
+		case EndpointJsz:
+			resp, err := client.JszPing(JszEventOptions{})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("polling JSZ: %w", err))
+				continue
+			}
+			store.AddJsz(now, resp)
+		default:
+			errs = append(errs, fmt.Errorf("%w: endpoint %s is not supported", ErrValidation, endpoint))
+		}
+	}
+	return errs
+}
+
+// WriteJSON writes the result as JSON.
+func (r *HistoryResult) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes a CSV row for each point, preceded by a header.
+func (r *HistoryResult) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "server", "server_name", "metric", "value"}); err != nil {
+		return err
+	}
+	for _, series := range r.Series {
+		for _, point := range series.Points {
+			row := []string{
+				point.Time.Format(time.RFC3339Nano),
+				series.Server,
+				series.ServerName,
+				series.Metric,
+				strconv.FormatFloat(point.Value, 'f', -1, 64),
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *ring) push(p Point) {
+	if len(r.points) < r.capacity {
+		r.points = append(r.points, p)
+		return
+	}
+	r.points[r.start] = p
+	r.start = (r.start + 1) % len(r.points)
+}
+
+// each calls fn for each point, from the oldest one.
+func (r *ring) each(fn func(Point)) {
+	for i := range r.points {
+		fn(r.points[(r.start+i)%len(r.points)])
+	}
+}
+
+func aggregation(a Aggregation) (func([]float64) float64, error) {
+	switch a {
+	case "", AggregateAvg:
+		return func(values []float64) float64 {
+			var sum float64
+			for _, v := range values {
+				sum += v
+			}
+			return sum / float64(len(values))
+		}, nil
+	case AggregateMin:
+		return func(values []float64) float64 {
+			res := math.Inf(1)
+			for _, v := range values {
+				res = math.Min(res, v)
+			}
+			return res
+		}, nil
+	case AggregateMax:
+		return func(values []float64) float64 {
+			res := math.Inf(-1)
+			for _, v := range values {
+				res = math.Max(res, v)
+			}
+			return res
+		}, nil
+	case AggregateLast:
+		return func(values []float64) float64 {
+			return values[len(values)-1]
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown aggregation: %q", ErrValidation, a)
+	}
+}
+
+// downsample aggregates sorted points into buckets of given size.
+// Each bucket is represented by a point at the bucket start time.
+func downsample(points []Point, step time.Duration, aggregate func([]float64) float64) []Point {
+	res := make([]Point, 0)
+	var bucket time.Time
+	var values []float64
+	for _, point := range points {
+		start := point.Time.Truncate(step)
+		if len(values) > 0 && !start.Equal(bucket) {
+			res = append(res, Point{Time: bucket, Value: aggregate(values)})
+			values = values[:0]
+		}
+		bucket = start
+		values = append(values, point.Value)
+	}
+	if len(values) > 0 {
+		res = append(res, Point{Time: bucket, Value: aggregate(values)})
+	}
+	return res
+}
@@ -0,0 +1,216 @@
+package sys
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestPollHistory(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	file := filepath.Join(t.TempDir(), "history.json")
+	store, err := NewHistoryStore(HistoryFile(file))
+	if err != nil {
+		t.Fatalf("Unable to create history store: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	for err := range PollHistory(ctx, sys, store, HistoryPollOptions{Interval: 100 * time.Millisecond, FlushInterval: 100 * time.Millisecond}) {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// store is flushed periodically
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("Expected history file to be written: %s", err)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Unable to flush history: %s", err)
+	}
+
+	res, err := store.Query(HistoryQuery{Server: "s1", Metric: "statsz.connections"})
+	if err != nil {
+		t.Fatalf("Unable to query history: %s", err)
+	}
+	if len(res.Series) != 1 || res.Series[0].ServerName != "s1" || len(res.Series[0].Points) < 2 {
+		t.Fatalf("Invalid series: %+v", res.Series)
+	}
+	res, err = store.Query(HistoryQuery{Metric: "jsz.storage"})
+	if err != nil {
+		t.Fatalf("Unable to query history: %s", err)
+	}
+	if len(res.Series) != 3 {
+		t.Fatalf("Invalid number of series: %d; want: %d", len(res.Series), 3)
+	}
+
+	// samples are restored from the file
+	restored, err := NewHistoryStore(HistoryFile(file))
+	if err != nil {
+		t.Fatalf("Unable to create history store: %s", err)
+	}
+	all, err := store.Query(HistoryQuery{})
+	if err != nil {
+		t.Fatalf("Unable to query history: %s", err)
+	}
+	allRestored, err := restored.Query(HistoryQuery{})
+	if err != nil {
+		t.Fatalf("Unable to query history: %s", err)
+	}
+	if len(all.Series) == 0 || len(all.Series) != len(allRestored.Series) {
+		t.Fatalf("Invalid number of restored series; want: %d; got: %d", len(all.Series), len(allRestored.Series))
+	}
+}
+
+func TestHistoryStoreQuery(t *testing.T) {
+	store, err := NewHistoryStore(HistoryCapacity(4))
+	if err != nil {
+		t.Fatalf("Unable to create history store: %s", err)
+	}
+	start := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	s1 := ServerInfo{ID: "S1", Name: "s1"}
+	s2 := ServerInfo{ID: "S2", Name: "s2"}
+	for i := 0; i < 6; i++ {
+		store.Add(s1, start.Add(time.Duration(i)*time.Minute), map[string]float64{"varz.mem": float64(i), "varz.cpu": 1})
+	}
+	store.Add(s2, start, map[string]float64{"varz.mem": 100})
+	// buffers grow up to the capacity
+	if points := store.series[seriesKey{server: "S2", metric: "varz.mem"}].points; len(points) != 1 || cap(points) >= 4 {
+		t.Fatalf("Invalid buffer size: len %d, cap %d", len(points), cap(points))
+	}
+
+	tests := []struct {
+		name      string
+		query     HistoryQuery
+		expected  []Point
+		withError error
+	}{
+		{
+			name:  "oldest samples are dropped",
+			query: HistoryQuery{Server: "S1", Metric: "varz.mem"},
+			expected: []Point{
+				{Time: start.Add(2 * time.Minute), Value: 2},
+				{Time: start.Add(3 * time.Minute), Value: 3},
+				{Time: start.Add(4 * time.Minute), Value: 4},
+				{Time: start.Add(5 * time.Minute), Value: 5},
+			},
+		},
+		{
+			name:  "time range",
+			query: HistoryQuery{Server: "s1", Metric: "varz.mem", From: start.Add(3 * time.Minute), To: start.Add(4 * time.Minute)},
+			expected: []Point{
+				{Time: start.Add(3 * time.Minute), Value: 3},
+				{Time: start.Add(4 * time.Minute), Value: 4},
+			},
+		},
+		{
+			name:  "downsampling with average",
+			query: HistoryQuery{Server: "s1", Metric: "varz.mem", Step: 2 * time.Minute},
+			expected: []Point{
+				{Time: start.Add(2 * time.Minute), Value: 2.5},
+				{Time: start.Add(4 * time.Minute), Value: 4.5},
+			},
+		},
+		{
+			name:  "downsampling with max",
+			query: HistoryQuery{Server: "s1", Metric: "varz.mem", Step: 4 * time.Minute, Aggregation: AggregateMax},
+			expected: []Point{
+				{Time: start, Value: 3},
+				{Time: start.Add(4 * time.Minute), Value: 5},
+			},
+		},
+		{
+			name:      "unknown aggregation",
+			query:     HistoryQuery{Step: time.Minute, Aggregation: "median"},
+			withError: ErrValidation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := store.Query(test.query)
+			if test.withError != nil {
+				if !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error: %s; got: %v", test.withError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if len(res.Series) != 1 {
+				t.Fatalf("Invalid number of series: %d; want: %d", len(res.Series), 1)
+			}
+			points := res.Series[0].Points
+			if len(points) != len(test.expected) {
+				t.Fatalf("Invalid points; want: %v; got: %v", test.expected, points)
+			}
+			for i, point := range test.expected {
+				if !points[i].Time.Equal(point.Time) || points[i].Value != point.Value {
+					t.Fatalf("Invalid points; want: %v; got: %v", test.expected, points)
+				}
+			}
+		})
+	}
+
+	res, err := store.Query(HistoryQuery{Metric: "varz.mem"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(res.Series) != 2 || res.Series[0].ServerName != "s1" || res.Series[1].ServerName != "s2" {
+		t.Fatalf("Invalid series: %+v", res.Series)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := res.WriteCSV(buf); err != nil {
+		t.Fatalf("Unable to write CSV: %s", err)
+	}
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Unable to read CSV: %s", err)
+	}
+	if len(rows) != 6 || rows[5][0] != "2023-03-01T12:00:00Z" || rows[5][2] != "s2" || rows[5][4] != "100" {
+		t.Fatalf("Invalid CSV output: %v", rows)
+	}
+}
+
+func TestMetricNames(t *testing.T) {
+	names := MetricNames()
+	for _, name := range []string{"varz.mem", "statsz.connections", "jsz.storage"} {
+		if !IsMetric(name) {
+			t.Fatalf("Expected %q to be a metric", name)
+		}
+	}
+	if IsMetric("varz.unknown") {
+		t.Fatalf("Expected %q not to be a metric", "varz.unknown")
+	}
+	if len(names) != len(varzMetrics)+len(statszMetrics)+len(jszMetrics) {
+		t.Fatalf("Invalid number of metrics: %d", len(names))
+	}
+	metrics := JszMetrics(&JSZResp{JSInfo: JSInfo{Disabled: true}})
+	if len(metrics) != 0 {
+		t.Fatalf("Expected no metrics for disabled JetStream; got: %v", metrics)
+	}
+}
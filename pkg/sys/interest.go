@@ -0,0 +1,167 @@
+package sys
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxInterestResults limits the number of subscriptions and connections fetched from each server by WhoReceives.
+const maxInterestResults = 10000
+
+type (
+	// InterestReport lists client subscriptions which would receive a message published on a subject.
+	InterestReport struct {
+		Account string `json:"account,omitempty"`
+		Subject string `json:"subject"`
+
+		// Receivers is the number of copies of a message which would be delivered to clients:
+		// one for each plain subscription and one for each queue group.
+		Receivers     int                  `json:"receivers"`
+		Subscriptions []Interest           `json:"subscriptions,omitempty"`
+		QueueGroups   []QueueGroupInterest `json:"queue_groups,omitempty"`
+
+		// Unresolved is the number of matching subscriptions not owned by a client connection,
+		// e.g. interest propagated from other servers over routes, gateways and leafnodes or internal subscriptions.
+		Unresolved int `json:"unresolved"`
+	}
+
+	// Interest is a matching subscription along with the client connection owning it.
+	Interest struct {
+		Server       string    `json:"server"`
+		ServerID     string    `json:"server_id"`
+		Subscription SubDetail `json:"subscription"`
+		Conn         *ConnInfo `json:"connection"`
+	}
+
+	// QueueGroupInterest describes distribution of matching queue subscriptions.
+	// Only one member of a queue group receives the message.
+	QueueGroupInterest struct {
+		Queue   string         `json:"queue"`
+		Subject string         `json:"subject"`
+		Members int            `json:"members"`
+		Servers map[string]int `json:"servers"`
+	}
+)
+
+// WhoReceives checks which client subscriptions on all servers match a literal publish subject.
+// If account is empty, subscriptions from all accounts are checked.
+func (a *Analyzer) WhoReceives(account, subject string) (*InterestReport, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("%w: subject cannot be empty", ErrValidation)
+	}
+	if !isLiteralSubject(subject) {
+		return nil, fmt.Errorf("%w: subject has to be a literal publish subject: %q", ErrValidation, subject)
+	}
+
+	subsz, err := a.client.ServerSubszPing(SubszOptions{
+		Account:       account,
+		Test:          subject,
+		Subscriptions: true,
+		Limit:         maxInterestResults,
+	})
+	if err != nil {
+		return nil, err
+	}
+	connzOpts := ConnzEventOptions{
+		ConnzOptions: ConnzOptions{
+			Username: true,
+			Account:  account,
+			Limit:    maxInterestResults,
+		},
+	}
+	// filtering by subject is only supported when filtering by account
+	if account != "" {
+		connzOpts.FilterSubject = subject
+	}
+	connz, err := a.client.ConnzPing(connzOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return whoReceives(account, subject, subsz, connz), nil
+}
+
+func whoReceives(account, subject string, subsz []SubszResp, connz []ConnzResp) *InterestReport {
+	report := &InterestReport{
+		Account: account,
+		Subject: subject,
+	}
+
+	type connKey struct {
+		server string
+		cid    uint64
+	}
+	conns := make(map[connKey]*ConnInfo)
+	for _, resp := range connz {
+		for _, conn := range resp.Connz.Conns {
+			if conn != nil {
+				conns[connKey{server: resp.Server.ID, cid: conn.Cid}] = conn
+			}
+		}
+	}
+
+	queues := make(map[string]*QueueGroupInterest)
+	for _, resp := range subsz {
+		for _, sub := range resp.Subsz.Subs {
+			conn, ok := conns[connKey{server: resp.Server.ID, cid: sub.Cid}]
+			if !ok {
+				report.Unresolved++
+				continue
+			}
+			report.Subscriptions = append(report.Subscriptions, Interest{
+				Server:       serverName(resp.Server),
+				ServerID:     resp.Server.ID,
+				Subscription: sub,
+				Conn:         conn,
+			})
+			if sub.Queue == "" {
+				report.Receivers++
+				continue
+			}
+			key := sub.Account + " " + sub.Subject + " " + sub.Queue
+			queue, ok := queues[key]
+			if !ok {
+				queue = &QueueGroupInterest{
+					Queue:   sub.Queue,
+					Subject: sub.Subject,
+					Servers: make(map[string]int),
+				}
+				queues[key] = queue
+				report.Receivers++
+			}
+			queue.Members++
+			queue.Servers[serverName(resp.Server)]++
+		}
+	}
+
+	sort.SliceStable(report.Subscriptions, func(i, j int) bool {
+		if report.Subscriptions[i].Server != report.Subscriptions[j].Server {
+			return report.Subscriptions[i].Server < report.Subscriptions[j].Server
+		}
+		return report.Subscriptions[i].Subscription.Cid < report.Subscriptions[j].Subscription.Cid
+	})
+	for _, queue := range queues {
+		report.QueueGroups = append(report.QueueGroups, *queue)
+	}
+	sort.Slice(report.QueueGroups, func(i, j int) bool {
+		if report.QueueGroups[i].Queue != report.QueueGroups[j].Queue {
+			return report.QueueGroups[i].Queue < report.QueueGroups[j].Queue
+		}
+		return report.QueueGroups[i].Subject < report.QueueGroups[j].Subject
+	})
+	return report
+}
+
+// isLiteralSubject checks whether the subject can be used to publish messages.
+func isLiteralSubject(subject string) bool {
+	if strings.ContainsAny(subject, " \t\r\n") {
+		return false
+	}
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" || token == "*" || token == ">" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package sys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestWhoReceives(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	subscribe := func(server int, name, subject, queue string) {
+		nc, err := nats.Connect(c.servers[server].ClientURL(), nats.Name(name))
+		if err != nil {
+			t.Fatalf("Error establishing connection: %s", err)
+		}
+		t.Cleanup(nc.Close)
+		if queue == "" {
+			_, err = nc.SubscribeSync(subject)
+		} else {
+			_, err = nc.QueueSubscribeSync(subject, queue)
+		}
+		if err != nil {
+			t.Fatalf("Error subscribing: %s", err)
+		}
+		if err := nc.Flush(); err != nil {
+			t.Fatalf("Error flushing connection: %s", err)
+		}
+	}
+	subscribe(0, "plain", "foo.>", "")
+	subscribe(0, "q1", "foo.bar", "q")
+	subscribe(1, "q2", "foo.bar", "q")
+	subscribe(2, "other", "bar", "")
+	time.Sleep(100 * time.Millisecond)
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	tests := []struct {
+		name              string
+		account           string
+		subject           string
+		expectedSubs      []string
+		expectedReceivers int
+		withError         error
+	}{
+		{
+			name:              "plain and queue subscriptions",
+			account:           "JS",
+			subject:           "foo.bar",
+			expectedSubs:      []string{"plain", "q1", "q2"},
+			expectedReceivers: 2,
+		},
+		{
+			name:              "all accounts",
+			subject:           "foo.baz",
+			expectedSubs:      []string{"plain"},
+			expectedReceivers: 1,
+		},
+		{
+			name:              "no interest",
+			account:           "JS",
+			subject:           "baz",
+			expectedReceivers: 0,
+		},
+		{
+			name:      "empty subject",
+			account:   "JS",
+			withError: ErrValidation,
+		},
+		{
+			name:      "wildcard subject",
+			account:   "JS",
+			subject:   "foo.*",
+			withError: ErrValidation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := sys.WhoReceives(test.account, test.subject)
+			if test.withError != nil {
+				if !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error; want: %s; got: %s", test.withError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unable to check subject interest: %s", err)
+			}
+			if report.Receivers != test.expectedReceivers {
+				t.Fatalf("Invalid number of receivers; want: %d; got: %d", test.expectedReceivers, report.Receivers)
+			}
+			names := make([]string, 0)
+			for _, sub := range report.Subscriptions {
+				names = append(names, sub.Conn.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(test.expectedSubs, ",") {
+				t.Fatalf("Invalid subscriptions; want: %v; got: %v", test.expectedSubs, names)
+			}
+		})
+	}
+
+	report, err := sys.WhoReceives("JS", "foo.bar")
+	if err != nil {
+		t.Fatalf("Unable to check subject interest: %s", err)
+	}
+	if len(report.QueueGroups) != 1 {
+		t.Fatalf("Invalid number of queue groups: %+v", report.QueueGroups)
+	}
+	queue := report.QueueGroups[0]
+	if queue.Queue != "q" || queue.Members != 2 || len(queue.Servers) != 2 {
+		t.Fatalf("Invalid queue group distribution: %+v", queue)
+	}
+}
@@ -0,0 +1,112 @@
+package sys
+
+import "sort"
+
+// Metric names have "<endpoint>.<field>" format, e.g. "varz.mem" or "jsz.storage".
+var (
+	varzMetrics = map[string]func(*Varz) float64{
+		"varz.mem":               func(v *Varz) float64 { return float64(v.Mem) },
+		"varz.cpu":               func(v *Varz) float64 { return v.CPU },
+		"varz.connections":       func(v *Varz) float64 { return float64(v.Connections) },
+		"varz.total_connections": func(v *Varz) float64 { return float64(v.TotalConnections) },
+		"varz.subscriptions":     func(v *Varz) float64 { return float64(v.Subscriptions) },
+		"varz.slow_consumers":    func(v *Varz) float64 { return float64(v.SlowConsumers) },
+		"varz.routes":            func(v *Varz) float64 { return float64(v.Routes) },
+		"varz.leafnodes":         func(v *Varz) float64 { return float64(v.Leafs) },
+		"varz.in_msgs":           func(v *Varz) float64 { return float64(v.InMsgs) },
+		"varz.out_msgs":          func(v *Varz) float64 { return float64(v.OutMsgs) },
+		"varz.in_bytes":          func(v *Varz) float64 { return float64(v.InBytes) },
+		"varz.out_bytes":         func(v *Varz) float64 { return float64(v.OutBytes) },
+	}
+
+	statszMetrics = map[string]func(*ServerStats) float64{
+		"statsz.mem":               func(s *ServerStats) float64 { return float64(s.Mem) },
+		"statsz.cpu":               func(s *ServerStats) float64 { return s.CPU },
+		"statsz.connections":       func(s *ServerStats) float64 { return float64(s.Connections) },
+		"statsz.total_connections": func(s *ServerStats) float64 { return float64(s.TotalConnections) },
+		"statsz.active_accounts":   func(s *ServerStats) float64 { return float64(s.ActiveAccounts) },
+		"statsz.subscriptions":     func(s *ServerStats) float64 { return float64(s.NumSubs) },
+		"statsz.slow_consumers":    func(s *ServerStats) float64 { return float64(s.SlowConsumers) },
+		"statsz.sent.msgs":         func(s *ServerStats) float64 { return float64(s.Sent.Msgs) },
+		"statsz.sent.bytes":        func(s *ServerStats) float64 { return float64(s.Sent.Bytes) },
+		"statsz.received.msgs":     func(s *ServerStats) float64 { return float64(s.Received.Msgs) },
+		"statsz.received.bytes":    func(s *ServerStats) float64 { return float64(s.Received.Bytes) },
+		"statsz.active_servers":    func(s *ServerStats) float64 { return float64(s.ActiveServers) },
+	}
+
+	jszMetrics = map[string]func(*JSInfo) float64{
+		"jsz.memory":           func(j *JSInfo) float64 { return float64(j.Memory) },
+		"jsz.storage":          func(j *JSInfo) float64 { return float64(j.Store) },
+		"jsz.reserved_memory":  func(j *JSInfo) float64 { return float64(j.ReservedMemory) },
+		"jsz.reserved_storage": func(j *JSInfo) float64 { return float64(j.ReservedStore) },
+		"jsz.accounts":         func(j *JSInfo) float64 { return float64(j.Accounts) },
+		"jsz.ha_assets":        func(j *JSInfo) float64 { return float64(j.HAAssets) },
+		"jsz.streams":          func(j *JSInfo) float64 { return float64(j.Streams) },
+		"jsz.consumers":        func(j *JSInfo) float64 { return float64(j.Consumers) },
+		"jsz.messages":         func(j *JSInfo) float64 { return float64(j.Messages) },
+		"jsz.bytes":            func(j *JSInfo) float64 { return float64(j.Bytes) },
+		"jsz.api.total":        func(j *JSInfo) float64 { return float64(j.API.Total) },
+		"jsz.api.errors":       func(j *JSInfo) float64 { return float64(j.API.Errors) },
+		"jsz.max_memory":       func(j *JSInfo) float64 { return float64(j.Config.MaxMemory) },
+		"jsz.max_storage":      func(j *JSInfo) float64 { return float64(j.Config.MaxStore) },
+	}
+)
+
+// MetricNames returns names of all metrics which can be extracted from monitoring responses, sorted.
+func MetricNames() []string {
+	names := make([]string, 0, len(varzMetrics)+len(statszMetrics)+len(jszMetrics))
+	for name := range varzMetrics {
+		names = append(names, name)
+	}
+	for name := range statszMetrics {
+		names = append(names, name)
+	}
+	for name := range jszMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsMetric checks whether the metric can be extracted from monitoring responses.
+func IsMetric(name string) bool {
+	if _, ok := varzMetrics[name]; ok {
+		return true
+	}
+	if _, ok := statszMetrics[name]; ok {
+		return true
+	}
+	_, ok := jszMetrics[name]
+	return ok
+}
+
+// VarzMetrics extracts numeric metrics from a VARZ response.
+func VarzMetrics(resp *VarzResp) map[string]float64 {
+	metrics := make(map[string]float64, len(varzMetrics))
+	for name, metric := range varzMetrics {
+		metrics[name] = metric(&resp.Varz)
+	}
+	return metrics
+}
+
+// StatszMetrics extracts numeric metrics from a STATSZ response.
+func StatszMetrics(resp *ServerStatszResp) map[string]float64 {
+	metrics := make(map[string]float64, len(statszMetrics))
+	for name, metric := range statszMetrics {
+		metrics[name] = metric(&resp.Statsz)
+	}
+	return metrics
+}
+
+// JszMetrics extracts numeric metrics from a JSZ response.
+// No metrics are returned for servers with JetStream disabled.
+func JszMetrics(resp *JSZResp) map[string]float64 {
+	if resp.JSInfo.Disabled {
+		return map[string]float64{}
+	}
+	metrics := make(map[string]float64, len(jszMetrics))
+	for name, metric := range jszMetrics {
+		metrics[name] = metric(&resp.JSInfo)
+	}
+	return metrics
+}
@@ -0,0 +1,71 @@
+package sys
+
+// defaultPageSize is the number of connections and subscriptions requested from a server at once
+// when fetching complete lists.
+const defaultPageSize = 1024
+
+// connzAll fetches connections matching the options from all servers.
+// Servers reporting more connections than returned in the first response
+// are paged through until all connections are fetched.
+func connzAll(client SysClient, opts ConnzEventOptions) ([]ConnzResp, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+	opts.Offset = 0
+	connz, err := client.ConnzPing(opts)
+	if err != nil {
+		return nil, err
+	}
+	for i, resp := range connz {
+		conns := resp.Connz.Conns
+		for len(conns) < resp.Connz.Total {
+			opts.Offset = len(conns)
+			next, err := client.Connz(resp.Server.ID, opts)
+			if err != nil {
+				return nil, err
+			}
+			if len(next.Connz.Conns) == 0 {
+				break
+			}
+			conns = append(conns, next.Connz.Conns...)
+		}
+		connz[i].Connz.Conns = conns
+		connz[i].Connz.NumConns = len(conns)
+		connz[i].Connz.Limit = len(conns)
+	}
+	return connz, nil
+}
+
+// subszAll fetches subscriptions matching the options from all servers.
+// Servers reporting more subscriptions than returned in the first response
+// are paged through until all subscriptions are fetched.
+func subszAll(client SysClient, opts SubszOptions) ([]SubszResp, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+	opts.Offset = 0
+	subsz, err := client.ServerSubszPing(opts)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Subscriptions {
+		return subsz, nil
+	}
+	for i, resp := range subsz {
+		subs := resp.Subsz.Subs
+		for len(subs) < resp.Subsz.Total {
+			opts.Offset = len(subs)
+			next, err := client.ServerSubsz(resp.Server.ID, opts)
+			if err != nil {
+				return nil, err
+			}
+			if len(next.Subsz.Subs) == 0 {
+				break
+			}
+			subs = append(subs, next.Subsz.Subs...)
+		}
+		subsz[i].Subsz.Subs = subs
+		subsz[i].Subsz.Limit = len(subs)
+	}
+	return subsz, nil
+}
@@ -0,0 +1,144 @@
+package sys
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestConnzAll(t *testing.T) {
+	conns := func(n int) []*ConnInfo {
+		res := make([]*ConnInfo, 0, n)
+		for i := 1; i <= n; i++ {
+			res = append(res, &ConnInfo{Cid: uint64(i), Account: "A"})
+		}
+		return res
+	}
+	source := NewSnapshotSource(&Snapshot{Connz: []ConnzResp{
+		{Server: ServerInfo{ID: "S1", Name: "s1"}, Connz: Connz{Conns: conns(5)}},
+		{Server: ServerInfo{ID: "S2", Name: "s2"}, Connz: Connz{Conns: conns(2)}},
+		{Server: ServerInfo{ID: "S3", Name: "s3"}, Connz: Connz{Conns: conns(defaultPageSize + 10)}},
+	}})
+
+	tests := []struct {
+		name     string
+		opts     ConnzEventOptions
+		expected map[string]int
+	}{
+		{
+			name:     "default page size",
+			expected: map[string]int{"s1": 5, "s2": 2, "s3": defaultPageSize + 10},
+		},
+		{
+			name:     "custom page size",
+			opts:     ConnzEventOptions{ConnzOptions: ConnzOptions{Limit: 2, Offset: 3}},
+			expected: map[string]int{"s1": 5, "s2": 2, "s3": defaultPageSize + 10},
+		},
+		{
+			name:     "with event filter",
+			opts:     ConnzEventOptions{ConnzOptions: ConnzOptions{Limit: 2}, EventFilterOptions: EventFilterOptions{Name: "s1"}},
+			expected: map[string]int{"s1": 5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connz, err := connzAll(source, test.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			counts := make(map[string]int)
+			for _, resp := range connz {
+				counts[resp.Server.Name] = len(resp.Connz.Conns)
+				if resp.Connz.NumConns != len(resp.Connz.Conns) {
+					t.Fatalf("Invalid number of connections: %d; want: %d", resp.Connz.NumConns, len(resp.Connz.Conns))
+				}
+				for i, conn := range resp.Connz.Conns {
+					if conn.Cid != uint64(i+1) {
+						t.Fatalf("Invalid connection order on %s: %d at %d", resp.Server.Name, conn.Cid, i)
+					}
+				}
+			}
+			if !reflect.DeepEqual(counts, test.expected) {
+				t.Fatalf("Invalid connections; want: %v; got: %v", test.expected, counts)
+			}
+		})
+	}
+
+	inv, err := NewAnalyzer(source).ClientInventory(ClientInventoryOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch client inventory: %s", err)
+	}
+	if inv.Total != defaultPageSize+17 {
+		t.Fatalf("Invalid number of connections: %d; want: %d", inv.Total, defaultPageSize+17)
+	}
+}
+
+func TestSubszAll(t *testing.T) {
+	subs := func(n int) []SubDetail {
+		res := make([]SubDetail, 0, n)
+		for i := 1; i <= n; i++ {
+			res = append(res, SubDetail{Account: "A", Subject: "foo", Sid: strconv.Itoa(i), Cid: uint64(i)})
+		}
+		return res
+	}
+	conns := make([]*ConnInfo, 0, defaultPageSize+10)
+	for _, sub := range subs(defaultPageSize + 10) {
+		conns = append(conns, &ConnInfo{Cid: sub.Cid, Account: "A", NumSubs: 1, SubsDetail: []SubDetail{sub}})
+	}
+	source := NewSnapshotSource(&Snapshot{
+		Subsz: []SubszResp{
+			{Server: ServerInfo{ID: "S1", Name: "s1"}, Subsz: Subsz{Subs: subs(5)}},
+			{Server: ServerInfo{ID: "S2", Name: "s2"}, Subsz: Subsz{Subs: subs(defaultPageSize + 10)}},
+		},
+		Connz: []ConnzResp{
+			{Server: ServerInfo{ID: "S1", Name: "s1"}, Connz: Connz{Conns: conns[:5]}},
+			{Server: ServerInfo{ID: "S2", Name: "s2"}, Connz: Connz{Conns: conns}},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		opts     SubszOptions
+		expected map[string]int
+	}{
+		{
+			name:     "without subscriptions",
+			expected: map[string]int{"s1": 0, "s2": 0},
+		},
+		{
+			name:     "default page size",
+			opts:     SubszOptions{Subscriptions: true},
+			expected: map[string]int{"s1": 5, "s2": defaultPageSize + 10},
+		},
+		{
+			name:     "custom page size",
+			opts:     SubszOptions{Subscriptions: true, Limit: 2},
+			expected: map[string]int{"s1": 5, "s2": defaultPageSize + 10},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subsz, err := subszAll(source, test.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			counts := make(map[string]int)
+			for _, resp := range subsz {
+				counts[resp.Server.Name] = len(resp.Subsz.Subs)
+			}
+			if !reflect.DeepEqual(counts, test.expected) {
+				t.Fatalf("Invalid subscriptions; want: %v; got: %v", test.expected, counts)
+			}
+		})
+	}
+
+	tree, err := NewAnalyzer(source).SubjectTree("A")
+	if err != nil {
+		t.Fatalf("Unable to build subject tree: %s", err)
+	}
+	if tree.Root.Total.Subscribers != defaultPageSize+15 {
+		t.Fatalf("Invalid number of subscribers: %d; want: %d", tree.Root.Total.Subscribers, defaultPageSize+15)
+	}
+}
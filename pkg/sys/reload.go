@@ -0,0 +1,155 @@
+package sys
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const srvReloadSubj = "$SYS.REQ.SERVER.%s.RELOAD"
+
+const (
+	DefaultReloadTimeout      = 30 * time.Second
+	DefaultReloadPollInterval = 500 * time.Millisecond
+)
+
+var (
+	ErrReloadNotApplied = errors.New("config load time did not advance after reload")
+	ErrServerUnhealthy  = errors.New("server is not healthy")
+)
+
+type (
+	// ReloadResp is the response to Reload.
+	ReloadResp struct {
+		ControlResp
+	}
+
+	// RollingReloadReport lists servers reloaded by RollingReload, in reload order.
+	RollingReloadReport struct {
+		Servers []ServerReload `json:"servers,omitempty"`
+	}
+
+	// ServerReload describes a verified config reload of a single server.
+	ServerReload struct {
+		Server           ServerInfo    `json:"server"`
+		PreviousLoadTime time.Time     `json:"previous_load_time"`
+		ConfigLoadTime   time.Time     `json:"config_load_time"`
+		VerificationTook time.Duration `json:"verification_took"`
+	}
+
+	// RollingReloadOptions are options passed to RollingReload
+	RollingReloadOptions struct {
+		// Servers is the list of server IDs to reload, in order.
+		// If empty, all servers responding to VARZ are reloaded, sorted by name.
+		Servers []string
+
+		// Timeout is the maximum time to wait for each server to confirm the reload and report healthy status,
+		// DefaultReloadTimeout by default.
+		Timeout time.Duration
+
+		// PollInterval is the time between VARZ and HEALTHZ checks, DefaultReloadPollInterval by default.
+		PollInterval time.Duration
+	}
+)
+
+// Reload triggers config reload on a server.
+// Requires AllowControl option.
+func (s *System) Reload(id string) (*ReloadResp, error) {
+	var resp ReloadResp
+	if err := s.controlRequest(srvReloadSubj, id, nil, &resp.ControlResp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RollingReload reloads config of servers one at a time.
+// After each reload, it waits until VARZ reports advanced config load time and HEALTHZ reports ok status.
+// It stops on the first failure and returns the servers reloaded so far, along with the error.
+// Requires AllowControl option.
+func (s *System) RollingReload(opts RollingReloadOptions) (*RollingReloadReport, error) {
+	if !s.opts.allowControl {
+		return nil, ErrControlDisabled
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultReloadTimeout
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultReloadPollInterval
+	}
+
+	servers := opts.Servers
+	if len(servers) == 0 {
+		varz, err := s.VarzPing(VarzEventOptions{})
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(varz, func(i, j int) bool {
+			return varz[i].Varz.Name < varz[j].Varz.Name
+		})
+		for _, resp := range varz {
+			servers = append(servers, resp.Server.ID)
+		}
+	}
+
+	report := &RollingReloadReport{}
+	for _, id := range servers {
+		reload, err := s.reloadAndVerify(id, opts)
+		if err != nil {
+			return report, fmt.Errorf("reloading server %q: %w", id, err)
+		}
+		report.Servers = append(report.Servers, *reload)
+	}
+	return report, nil
+}
+
+func (s *System) reloadAndVerify(id string, opts RollingReloadOptions) (*ServerReload, error) {
+	before, err := s.Varz(id, VarzEventOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Reload(id); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	deadline := start.Add(opts.Timeout)
+	reload := &ServerReload{
+		Server:           before.Server,
+		PreviousLoadTime: before.Varz.ConfigLoadTime,
+	}
+	for {
+		varz, err := s.Varz(id, VarzEventOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if varz.Varz.ConfigLoadTime.After(reload.PreviousLoadTime) {
+			reload.Server = varz.Server
+			reload.ConfigLoadTime = varz.Varz.ConfigLoadTime
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrReloadNotApplied
+		}
+		time.Sleep(opts.PollInterval)
+	}
+
+	for {
+		healthz, err := s.Healthz(id, HealthzOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if healthz.Healthz.Status == StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			if healthz.Healthz.Error != "" {
+				return nil, fmt.Errorf("%w: %s", ErrServerUnhealthy, healthz.Healthz.Error)
+			}
+			return nil, fmt.Errorf("%w: status %s", ErrServerUnhealthy, healthz.Healthz.Status)
+		}
+		time.Sleep(opts.PollInterval)
+	}
+	reload.VerificationTook = time.Since(start)
+	return reload, nil
+}
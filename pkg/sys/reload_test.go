@@ -0,0 +1,165 @@
+package sys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// handleReload simulates RELOAD system endpoint, which is not handled by servers used in tests.
+// If reload is false, the request is acknowledged, but config is not reloaded.
+func handleReload(t *testing.T, nc *nats.Conn, srv *server.Server, confFile string, reload bool) {
+	t.Helper()
+	_, err := nc.Subscribe(fmt.Sprintf(srvReloadSubj, srv.ID()), func(msg *nats.Msg) {
+		resp := ControlResp{Server: ServerInfo{ID: srv.ID(), Name: srv.Name()}}
+		if reload {
+			if err := reloadServer(srv, confFile); err != nil {
+				resp.Error = &ServerAPIError{Code: 500, Description: err.Error()}
+			}
+		}
+		data, _ := json.Marshal(resp)
+		msg.Respond(data)
+	})
+	if err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	if err := nc.Flush(); err != nil {
+		t.Fatalf("Error flushing: %s", err)
+	}
+}
+
+// reloadServer reloads config file, keeping options overridden in StartJetStreamServer.
+func reloadServer(srv *server.Server, confFile string) error {
+	opts, err := server.ProcessConfigFile(confFile)
+	if err != nil {
+		return err
+	}
+	opts.NoLog = true
+	opts.Port = srv.Addr().(*net.TCPAddr).Port
+	opts.StoreDir = filepath.Dir(srv.JetStreamConfig().StoreDir)
+	return srv.ReloadOptions(opts)
+}
+
+func TestReload(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	sysConn, err := nats.Connect(c.servers[0].ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+	handleReload(t, sysConn, c.servers[0], configFiles[0], true)
+
+	readOnly, err := NewSysClient(sysConn)
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	if _, err := readOnly.Reload(c.servers[0].ID()); !errors.Is(err, ErrControlDisabled) {
+		t.Fatalf("Expected error: %s; got: %v", ErrControlDisabled, err)
+	}
+
+	sys, err := NewSysClient(sysConn, AllowControl())
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	before := c.servers[0].ConfigTime()
+	resp, err := sys.Reload(c.servers[0].ID())
+	if err != nil {
+		t.Fatalf("Unable to reload server: %s", err)
+	}
+	if resp.Server.ID != c.servers[0].ID() {
+		t.Fatalf("Invalid server ID; want: %s; got: %s", c.servers[0].ID(), resp.Server.ID)
+	}
+	if !c.servers[0].ConfigTime().After(before) {
+		t.Fatalf("Expected config load time to advance")
+	}
+	if _, err := sys.Reload("unknown"); !errors.Is(err, ErrInvalidServerID) {
+		t.Fatalf("Expected error: %s; got: %v", ErrInvalidServerID, err)
+	}
+}
+
+func TestRollingReload(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	sys, err := NewSysClient(sysConn, AllowControl(), ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	t.Run("all servers", func(t *testing.T) {
+		responder, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+		if err != nil {
+			t.Fatalf("Error establishing connection: %s", err)
+		}
+		defer responder.Close()
+		for i, srv := range c.servers {
+			handleReload(t, responder, srv, configFiles[i], true)
+		}
+
+		report, err := sys.RollingReload(RollingReloadOptions{PollInterval: 50 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("Unable to reload servers: %s", err)
+		}
+		if len(report.Servers) != 3 {
+			t.Fatalf("Invalid number of reloaded servers: %d; want: %d", len(report.Servers), 3)
+		}
+		if !sort.SliceIsSorted(report.Servers, func(i, j int) bool {
+			return report.Servers[i].Server.Name < report.Servers[j].Server.Name
+		}) {
+			t.Fatalf("Servers not reloaded in order: %+v", report.Servers)
+		}
+		for _, reload := range report.Servers {
+			if !reload.ConfigLoadTime.After(reload.PreviousLoadTime) {
+				t.Fatalf("Config load time did not advance: %+v", reload)
+			}
+		}
+	})
+
+	t.Run("stop on first failure", func(t *testing.T) {
+		responder, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+		if err != nil {
+			t.Fatalf("Error establishing connection: %s", err)
+		}
+		defer responder.Close()
+		handleReload(t, responder, c.servers[0], configFiles[0], true)
+		handleReload(t, responder, c.servers[1], configFiles[1], false)
+		handleReload(t, responder, c.servers[2], configFiles[2], true)
+
+		lastReload := c.servers[2].ConfigTime()
+		report, err := sys.RollingReload(RollingReloadOptions{
+			Servers:      []string{c.servers[0].ID(), c.servers[1].ID(), c.servers[2].ID()},
+			Timeout:      500 * time.Millisecond,
+			PollInterval: 50 * time.Millisecond,
+		})
+		if !errors.Is(err, ErrReloadNotApplied) {
+			t.Fatalf("Expected error: %s; got: %v", ErrReloadNotApplied, err)
+		}
+		if len(report.Servers) != 1 || report.Servers[0].Server.ID != c.servers[0].ID() {
+			t.Fatalf("Invalid reloaded servers: %+v", report.Servers)
+		}
+		if !c.servers[2].ConfigTime().Equal(lastReload) {
+			t.Fatalf("Expected reload to stop on first failure")
+		}
+	})
+}
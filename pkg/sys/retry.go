@@ -0,0 +1,139 @@
+package sys
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 5 * time.Second
+	DefaultRetryMultiplier     = 2.0
+)
+
+// DefaultRetryableErrors are retried if RetryPolicy does not specify RetryOn.
+var DefaultRetryableErrors = []error{
+	nats.ErrTimeout,
+	nats.ErrConnectionClosed,
+	nats.ErrConnectionReconnecting,
+	nats.ErrDisconnected,
+	nats.ErrStaleConnection,
+}
+
+// RetryPolicy configures retries of failed system requests.
+// Requests failing because the server does not exist (ErrInvalidServerID, nats.ErrNoResponders) are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry, DefaultRetryInitialBackoff by default.
+	InitialBackoff time.Duration
+
+	// MaxBackoff limits the time between retries, DefaultRetryMaxBackoff by default.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor by which backoff grows after each retry, DefaultRetryMultiplier by default.
+	Multiplier float64
+
+	// Jitter is the fraction (between 0 and 1) by which each backoff is randomly shortened.
+	Jitter float64
+
+	// RetryOn lists errors which are retried, matched with errors.Is. DefaultRetryableErrors by default.
+	RetryOn []error
+}
+
+// SysRetryPolicy enables retrying monitoring requests.
+// Requests to a single server are retried if they fail with one of RetryOn errors, e.g. when they time out.
+// PING requests (RequestMany) are only retried if the request cannot be sent, e.g. because the connection
+// is closed. They are not retried if fewer servers than expected respond in time; the responses received
+// until then are returned without an error.
+// Control requests (e.g. KickClient or Reload) are not retried.
+func SysRetryPolicy(policy RetryPolicy) SysClientOpt {
+	return func(opts *sysClientOpts) error {
+		if policy.MaxAttempts <= 0 {
+			return fmt.Errorf("%w: max attempts has to be greater than 0", ErrValidation)
+		}
+		if policy.InitialBackoff < 0 || policy.MaxBackoff < 0 {
+			return fmt.Errorf("%w: backoff cannot be negative", ErrValidation)
+		}
+		if policy.Multiplier != 0 && policy.Multiplier < 1 {
+			return fmt.Errorf("%w: backoff multiplier cannot be less than 1", ErrValidation)
+		}
+		if policy.Jitter < 0 || policy.Jitter > 1 {
+			return fmt.Errorf("%w: jitter has to be between 0 and 1", ErrValidation)
+		}
+		if policy.InitialBackoff == 0 {
+			policy.InitialBackoff = DefaultRetryInitialBackoff
+		}
+		if policy.MaxBackoff == 0 {
+			policy.MaxBackoff = DefaultRetryMaxBackoff
+		}
+		if policy.Multiplier == 0 {
+			policy.Multiplier = DefaultRetryMultiplier
+		}
+		if len(policy.RetryOn) == 0 {
+			policy.RetryOn = DefaultRetryableErrors
+		}
+		opts.retry = &policy
+		return nil
+	}
+}
+
+// retryable checks whether a request which failed with err should be retried.
+func (p *RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, ErrInvalidServerID) || errors.Is(err, nats.ErrNoResponders) {
+		return false
+	}
+	for _, retryable := range p.RetryOn {
+		if errors.Is(err, retryable) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the time to wait before given retry (starting with 1).
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry && backoff < float64(p.MaxBackoff); i++ {
+		backoff *= p.Multiplier
+	}
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	backoff -= backoff * p.Jitter * rand.Float64()
+	return time.Duration(backoff)
+}
+
+// withRetry calls fn until it succeeds, fails with an error which is not retryable or runs out of attempts.
+func (s *System) withRetry(fn func() error) error {
+	policy := s.opts.retry
+	if policy == nil {
+		return fn()
+	}
+	var err error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(policy.backoff(attempt - 1))
+		}
+		if err = fn(); err == nil || !policy.retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// request sends a request to a single server, retrying it according to the retry policy.
+func (s *System) request(subj string, payload []byte) (*nats.Msg, error) {
+	var msg *nats.Msg
+	err := s.withRetry(func() error {
+		var err error
+		msg, err = s.nc.Request(subj, payload, s.opts.timeout)
+		return err
+	})
+	return msg, err
+}
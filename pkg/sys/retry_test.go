@@ -0,0 +1,168 @@
+package sys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	sysConn, err := nats.Connect(c.servers[0].ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	// responder ignoring the first two requests
+	var requests int32
+	_, err = sysConn.Subscribe(fmt.Sprintf(srvVarzSubj, "flaky"), func(msg *nats.Msg) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			return
+		}
+		data, _ := json.Marshal(VarzResp{Server: ServerInfo{ID: "flaky"}})
+		msg.Respond(data)
+	})
+	if err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	sysConn.Flush()
+
+	noRetry, err := NewSysClient(sysConn, SysRequestTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	if _, err := noRetry.Varz("flaky", VarzEventOptions{}); !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("Expected error: %s; got: %v", nats.ErrTimeout, err)
+	}
+
+	sys, err := NewSysClient(sysConn,
+		SysRequestTimeout(100*time.Millisecond),
+		SysRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond, Jitter: 0.5}),
+	)
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	atomic.StoreInt32(&requests, 1)
+	resp, err := sys.Varz("flaky", VarzEventOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch VARZ: %s", err)
+	}
+	if resp.Server.ID != "flaky" || atomic.LoadInt32(&requests) != 3 {
+		t.Fatalf("Invalid response: %+v; requests: %d", resp, requests)
+	}
+
+	// server which does not exist is not retried
+	start := time.Now()
+	if _, err := sys.Connz("unknown", ConnzEventOptions{}); !errors.Is(err, ErrInvalidServerID) {
+		t.Fatalf("Expected error: %s; got: %v", ErrInvalidServerID, err)
+	}
+	if elapsed := time.Since(start); elapsed >= 10*time.Millisecond {
+		t.Fatalf("Expected request not to be retried; took: %s", elapsed)
+	}
+
+	// partial responses are returned without retrying
+	partial, err := NewSysClient(sysConn,
+		ServerCount(4),
+		SysRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}),
+	)
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+	start = time.Now()
+	varz, err := partial.VarzPing(VarzEventOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch VARZ: %s", err)
+	}
+	if len(varz) != 3 {
+		t.Fatalf("Invalid number of responses: %d; want: %d", len(varz), 3)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Fatalf("Expected request not to be retried; took: %s", elapsed)
+	}
+
+	// closed connection is retried until attempts run out
+	sysConn.Close()
+	start = time.Now()
+	if _, err := sys.VarzPing(VarzEventOptions{}); !errors.Is(err, nats.ErrConnectionClosed) {
+		t.Fatalf("Expected error: %s; got: %v", nats.ErrConnectionClosed, err)
+	}
+	// minimum backoff of 2 retries with 50% jitter
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("Expected request to be retried; took: %s", elapsed)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     3,
+	}
+	expected := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second, time.Second}
+	for i, backoff := range expected {
+		if res := policy.backoff(i + 1); res != backoff {
+			t.Fatalf("Invalid backoff for retry %d; want: %s; got: %s", i+1, backoff, res)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if res := policy.backoff(1); res < 50*time.Millisecond || res > 100*time.Millisecond {
+			t.Fatalf("Backoff out of jitter range: %s", res)
+		}
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	opts := &sysClientOpts{}
+	if err := SysRetryPolicy(RetryPolicy{MaxAttempts: 2})(opts); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	errCustom := errors.New("custom")
+
+	tests := []struct {
+		name     string
+		retryOn  []error
+		err      error
+		expected bool
+	}{
+		{name: "timeout", err: nats.ErrTimeout, expected: true},
+		{name: "wrapped connection closed", err: fmt.Errorf("request: %w", nats.ErrConnectionClosed), expected: true},
+		{name: "no responders", err: nats.ErrNoResponders, expected: false},
+		{name: "invalid server ID", err: fmt.Errorf("%w: S1", ErrInvalidServerID), expected: false},
+		{name: "custom error", retryOn: []error{errCustom}, err: errCustom, expected: true},
+		{name: "timeout not in custom errors", retryOn: []error{errCustom}, err: nats.ErrTimeout, expected: false},
+		{name: "no responders cannot be retried", retryOn: []error{nats.ErrNoResponders}, err: nats.ErrNoResponders, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := *opts.retry
+			if test.retryOn != nil {
+				policy.RetryOn = test.retryOn
+			}
+			if res := policy.retryable(test.err); res != test.expected {
+				t.Fatalf("Invalid result; want: %t; got: %t", test.expected, res)
+			}
+		})
+	}
+
+	for _, invalid := range []RetryPolicy{
+		{MaxAttempts: 0},
+		{MaxAttempts: 2, Multiplier: 0.5},
+		{MaxAttempts: 2, Jitter: 2},
+		{MaxAttempts: 2, InitialBackoff: -time.Second},
+	} {
+		if err := SysRetryPolicy(invalid)(&sysClientOpts{}); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Expected error: %s; got: %v", ErrValidation, err)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package sys
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultServerCacheTTL = 30 * time.Second
+
+var ErrNoMatchingServers = errors.New("no servers match the selector")
+
+type (
+	// Selector selects servers by their properties.
+	// Unlike EventFilterOptions, names and clusters have to match exactly.
+	// Empty selector matches all servers.
+	Selector struct {
+		Name      string   `json:"name,omitempty"`
+		Cluster   string   `json:"cluster,omitempty"`
+		Tags      []string `json:"tags,omitempty"` // server has to have all tags
+		Domain    string   `json:"domain,omitempty"`
+		JetStream bool     `json:"jetstream,omitempty"` // only select servers with JetStream enabled
+	}
+
+	// serverResolver caches servers discovered with STATSZ.
+	serverResolver struct {
+		sync.Mutex
+		ttl       time.Duration
+		discover  func() ([]ServerInfo, error)
+		servers   []ServerInfo
+		expiresAt time.Time
+	}
+)
+
+// ServerCacheTTL sets the time for which servers discovered for selectors are cached, DefaultServerCacheTTL by default.
+func ServerCacheTTL(ttl time.Duration) SysClientOpt {
+	return func(opts *sysClientOpts) error {
+		if ttl <= 0 {
+			return fmt.Errorf("%w: server cache TTL has to be greater than 0", ErrValidation)
+		}
+		opts.serverCacheTTL = ttl
+		return nil
+	}
+}
+
+// Matches checks whether the server matches the selector.
+func (sel Selector) Matches(info ServerInfo) bool {
+	if sel.Name != "" && sel.Name != info.Name {
+		return false
+	}
+	if sel.Cluster != "" && sel.Cluster != info.Cluster {
+		return false
+	}
+	if sel.Domain != "" && sel.Domain != info.Domain {
+		return false
+	}
+	if sel.JetStream && !info.JetStream {
+		return false
+	}
+	for _, tag := range sel.Tags {
+		var found bool
+		for _, serverTag := range info.Tags {
+			if strings.EqualFold(tag, serverTag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (sel Selector) String() string {
+	parts := make([]string, 0)
+	if sel.Name != "" {
+		parts = append(parts, "name="+sel.Name)
+	}
+	if sel.Cluster != "" {
+		parts = append(parts, "cluster="+sel.Cluster)
+	}
+	if len(sel.Tags) > 0 {
+		parts = append(parts, "tags="+strings.Join(sel.Tags, ","))
+	}
+	if sel.Domain != "" {
+		parts = append(parts, "domain="+sel.Domain)
+	}
+	if sel.JetStream {
+		parts = append(parts, "jetstream")
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *System) newServerResolver() *serverResolver {
+	return &serverResolver{
+		ttl: s.opts.serverCacheTTL,
+		discover: func() ([]ServerInfo, error) {
+			statsz, err := s.ServerStatszPing(StatszEventOptions{})
+			if err != nil {
+				return nil, err
+			}
+			servers := make([]ServerInfo, 0, len(statsz))
+			for _, resp := range statsz {
+				servers = append(servers, resp.Server)
+			}
+			return servers, nil
+		},
+	}
+}
+
+// SelectServers returns servers matching the selector, sorted by name.
+// Servers are discovered with STATSZ and cached, see ServerCacheTTL.
+// ErrNoMatchingServers is returned if no server matches the selector.
+func (s *System) SelectServers(sel Selector) ([]ServerInfo, error) {
+	return s.resolver.resolve(sel)
+}
+
+// InvalidateServerCache forces servers to be discovered again on the next selector based request.
+func (s *System) InvalidateServerCache() {
+	s.resolver.invalidate()
+}
+
+func (r *serverResolver) resolve(sel Selector) ([]ServerInfo, error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.servers == nil || time.Now().After(r.expiresAt) {
+		servers, err := r.discover()
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(servers, func(i, j int) bool {
+			return servers[i].Name < servers[j].Name
+		})
+		r.servers = servers
+		r.expiresAt = time.Now().Add(r.ttl)
+	}
+
+	selected := make([]ServerInfo, 0)
+	for _, server := range r.servers {
+		if sel.Matches(server) {
+			selected = append(selected, server)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatchingServers, sel)
+	}
+	return selected, nil
+}
+
+func (r *serverResolver) invalidate() {
+	r.Lock()
+	defer r.Unlock()
+	r.servers = nil
+}
+
+// requestSelected calls the request for each selected server.
+// If any of the cached servers no longer exists, the cache is invalidated.
+func requestSelected[T any](s *System, sel Selector, request func(id string) (*T, error)) ([]T, error) {
+	servers, err := s.SelectServers(sel)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]T, 0, len(servers))
+	for _, server := range servers {
+		resp, err := request(server.ID)
+		if err != nil {
+			if errors.Is(err, ErrInvalidServerID) {
+				s.InvalidateServerCache()
+			}
+			return nil, err
+		}
+		res = append(res, *resp)
+	}
+	return res, nil
+}
+
+// VarzSelect returns general server information from all servers matching the selector.
+func (s *System) VarzSelect(sel Selector, opts VarzEventOptions) ([]VarzResp, error) {
+	return requestSelected(s, sel, func(id string) (*VarzResp, error) {
+		return s.Varz(id, opts)
+	})
+}
+
+// ConnzSelect returns connection information from all servers matching the selector.
+func (s *System) ConnzSelect(sel Selector, opts ConnzEventOptions) ([]ConnzResp, error) {
+	return requestSelected(s, sel, func(id string) (*ConnzResp, error) {
+		return s.Connz(id, opts)
+	})
+}
+
+// ServerSubszSelect returns subscription information from all servers matching the selector.
+func (s *System) ServerSubszSelect(sel Selector, opts SubszOptions) ([]SubszResp, error) {
+	return requestSelected(s, sel, func(id string) (*SubszResp, error) {
+		return s.ServerSubsz(id, opts)
+	})
+}
+
+// JszSelect returns JetStream information from all servers matching the selector.
+func (s *System) JszSelect(sel Selector, opts JszEventOptions) ([]JSZResp, error) {
+	return requestSelected(s, sel, func(id string) (*JSZResp, error) {
+		return s.Jsz(id, opts)
+	})
+}
+
+// ServerStatszSelect returns server statistics from all servers matching the selector.
+func (s *System) ServerStatszSelect(sel Selector, opts StatszEventOptions) ([]ServerStatszResp, error) {
+	return requestSelected(s, sel, func(id string) (*ServerStatszResp, error) {
+		return s.ServerStatsz(id, opts)
+	})
+}
+
+// HealthzSelect checks health status of all servers matching the selector.
+func (s *System) HealthzSelect(sel Selector, opts HealthzOptions) ([]HealthzResp, error) {
+	return requestSelected(s, sel, func(id string) (*HealthzResp, error) {
+		return s.Healthz(id, opts)
+	})
+}
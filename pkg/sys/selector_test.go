@@ -0,0 +1,167 @@
+package sys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestSelectServers(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		selector  Selector
+		expected  []string
+		withError error
+	}{
+		{name: "all servers", selector: Selector{}, expected: []string{"s1", "s2", "s3"}},
+		{name: "by name", selector: Selector{Name: "s2"}, expected: []string{"s2"}},
+		{name: "by cluster with JetStream", selector: Selector{Cluster: "C1", JetStream: true}, expected: []string{"s1", "s2", "s3"}},
+		{name: "name has to match exactly", selector: Selector{Name: "s"}, withError: ErrNoMatchingServers},
+		{name: "unknown cluster", selector: Selector{Cluster: "C2"}, withError: ErrNoMatchingServers},
+		{name: "unknown tag", selector: Selector{Tags: []string{"az:east"}}, withError: ErrNoMatchingServers},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			servers, err := sys.SelectServers(test.selector)
+			if test.withError != nil {
+				if !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error: %s; got: %v", test.withError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unable to select servers: %s", err)
+			}
+			if len(servers) != len(test.expected) {
+				t.Fatalf("Invalid number of servers; want: %d; got: %d", len(test.expected), len(servers))
+			}
+			for i, server := range servers {
+				if server.Name != test.expected[i] {
+					t.Fatalf("Invalid server; want: %s; got: %s", test.expected[i], server.Name)
+				}
+			}
+		})
+	}
+
+	jsz, err := sys.JszSelect(Selector{Cluster: "C1", JetStream: true}, JszEventOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch JSZ: %s", err)
+	}
+	if len(jsz) != 3 {
+		t.Fatalf("Invalid number of responses: %d; want: %d", len(jsz), 3)
+	}
+	varz, err := sys.VarzSelect(Selector{Name: "s3"}, VarzEventOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch VARZ: %s", err)
+	}
+	if len(varz) != 1 || varz[0].Varz.Name != "s3" {
+		t.Fatalf("Invalid VARZ response: %+v", varz)
+	}
+	healthz, err := sys.HealthzSelect(Selector{}, HealthzOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch HEALTHZ: %s", err)
+	}
+	for _, resp := range healthz {
+		if resp.Healthz.Status != StatusOK {
+			t.Fatalf("Invalid health status of %s: %s", resp.Server.Name, resp.Healthz.Status)
+		}
+	}
+
+	// requests to a cached server which no longer exists invalidate the cache
+	sys.resolver.Lock()
+	sys.resolver.servers = append(sys.resolver.servers, ServerInfo{Name: "s4", ID: "unknown"})
+	sys.resolver.Unlock()
+	if _, err := sys.VarzSelect(Selector{Name: "s4"}, VarzEventOptions{}); !errors.Is(err, ErrInvalidServerID) {
+		t.Fatalf("Expected error: %s; got: %v", ErrInvalidServerID, err)
+	}
+	if _, err := sys.SelectServers(Selector{Name: "s4"}); !errors.Is(err, ErrNoMatchingServers) {
+		t.Fatalf("Expected error: %s; got: %v", ErrNoMatchingServers, err)
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	server := ServerInfo{Name: "n1", Cluster: "C2", Domain: "hub", Tags: []string{"az:east", "ssd"}, JetStream: true}
+
+	tests := []struct {
+		name     string
+		selector Selector
+		expected bool
+	}{
+		{name: "empty selector", selector: Selector{}, expected: true},
+		{name: "all properties", selector: Selector{Name: "n1", Cluster: "C2", Domain: "hub", Tags: []string{"AZ:EAST"}, JetStream: true}, expected: true},
+		{name: "all tags have to match", selector: Selector{Tags: []string{"az:east", "hdd"}}, expected: false},
+		{name: "different cluster", selector: Selector{Cluster: "C1"}, expected: false},
+		{name: "different domain", selector: Selector{Domain: "leaf"}, expected: false},
+		{name: "JetStream enabled", selector: Selector{JetStream: true}, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if res := test.selector.Matches(server); res != test.expected {
+				t.Fatalf("Invalid result; want: %t; got: %t", test.expected, res)
+			}
+		})
+	}
+
+	if (Selector{JetStream: true}).Matches(ServerInfo{Name: "n2"}) {
+		t.Fatalf("Expected server without JetStream not to match")
+	}
+}
+
+func TestServerResolverCache(t *testing.T) {
+	var calls int
+	resolver := &serverResolver{
+		ttl: 50 * time.Millisecond,
+		discover: func() ([]ServerInfo, error) {
+			calls++
+			return []ServerInfo{{Name: "b", ID: "B"}, {Name: "a", ID: "A"}}, nil
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		servers, err := resolver.resolve(Selector{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(servers) != 2 || servers[0].ID != "A" {
+			t.Fatalf("Invalid servers: %+v", servers)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Invalid number of discovery requests: %d; want: %d", calls, 1)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := resolver.resolve(Selector{Name: "b"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	resolver.invalidate()
+	if _, err := resolver.resolve(Selector{Name: "b"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if calls != 3 {
+		t.Fatalf("Invalid number of discovery requests: %d; want: %d", calls, 3)
+	}
+}
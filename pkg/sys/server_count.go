@@ -0,0 +1,94 @@
+package sys
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// serverCounter holds the number of servers learned from STATSZ responses.
+type serverCounter struct {
+	sync.Mutex
+	ttl       time.Duration
+	count     int
+	expiresAt time.Time
+}
+
+// AutoServerCount makes PING requests complete as soon as all known servers respond,
+// instead of waiting for the request interval to pass without new responses.
+// The number of servers is learned from active server count reported in STATSZ responses,
+// including responses to server discovery used by selectors, and is cached for the server cache TTL.
+// If the number of servers is not known, it is discovered with a STATSZ PING before the request.
+// If fewer servers respond (e.g. because of EventFilterOptions), requests complete after the interval.
+// If more servers respond than expected (e.g. after the cluster was scaled up), the number of servers
+// is discovered again on the next request; servers responding after the request completed are not included.
+// It has no effect if ServerCount is set.
+func AutoServerCount() SysClientOpt {
+	return func(opts *sysClientOpts) error {
+		opts.autoServerCount = true
+		return nil
+	}
+}
+
+func (c *serverCounter) observe(statsz []ServerStatszResp) {
+	count := 0
+	for _, resp := range statsz {
+		if resp.Statsz.ActiveServers > count {
+			count = resp.Statsz.ActiveServers
+		}
+	}
+	if count == 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.count = count
+	c.expiresAt = time.Now().Add(c.ttl)
+}
+
+// get returns the number of known servers or -1 if it is not known.
+func (c *serverCounter) get() int {
+	c.Lock()
+	defer c.Unlock()
+	if c.count == 0 || time.Now().After(c.expiresAt) {
+		return -1
+	}
+	return c.count
+}
+
+// reset forgets the number of servers, so that it is discovered again.
+func (c *serverCounter) reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.count = 0
+}
+
+// knownServerCount returns the number of servers, discovering it with STATSZ if it is not known.
+// -1 is returned if the number of servers could not be discovered.
+func (s *System) knownServerCount(subject string) int {
+	if count := s.servers.get(); count != -1 {
+		return count
+	}
+	// STATSZ PING discovers the number of servers itself
+	if subject == fmt.Sprintf(srvStatszSubj, "PING") {
+		return -1
+	}
+	if _, err := s.ServerStatszPing(StatszEventOptions{}); err != nil {
+		return -1
+	}
+	return s.servers.get()
+}
+
+// awaitExtraResponses keeps listening for responses after all known servers responded to a PING request,
+// until no response is received within the request interval.
+// Any response means that the number of servers is too low, so it is discovered again on the next request.
+func (s *System) awaitExtraResponses(sub *nats.Subscription, msgs <-chan *nats.Msg, timer *time.Timer) {
+	defer sub.Unsubscribe()
+	select {
+	case <-msgs:
+		s.servers.reset()
+	case <-timer.C:
+	}
+}
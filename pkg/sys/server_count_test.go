@@ -0,0 +1,145 @@
+package sys
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestAutoServerCount(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	interval := 500 * time.Millisecond
+	sys, err := NewSysClient(sysConn, SysMultiRequestInterval(0, interval), AutoServerCount())
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	// number of servers is not known yet
+	start := time.Now()
+	statsz, err := sys.ServerStatszPing(StatszEventOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch STATSZ: %s", err)
+	}
+	if len(statsz) != 3 {
+		t.Fatalf("Invalid number of responses: %d; want: %d", len(statsz), 3)
+	}
+	if elapsed := time.Since(start); elapsed < interval {
+		t.Fatalf("Expected request to wait for the interval; took: %s", elapsed)
+	}
+
+	start = time.Now()
+	varz, err := sys.VarzPing(VarzEventOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch VARZ: %s", err)
+	}
+	if len(varz) != 3 {
+		t.Fatalf("Invalid number of responses: %d; want: %d", len(varz), 3)
+	}
+	if elapsed := time.Since(start); elapsed >= interval {
+		t.Fatalf("Expected request to complete once all servers responded; took: %s", elapsed)
+	}
+
+	// filtered requests fall back to the interval
+	start = time.Now()
+	varz, err = sys.VarzPing(VarzEventOptions{EventFilterOptions: EventFilterOptions{Name: "s1"}})
+	if err != nil {
+		t.Fatalf("Unable to fetch VARZ: %s", err)
+	}
+	if len(varz) != 1 {
+		t.Fatalf("Invalid number of responses: %d; want: %d", len(varz), 1)
+	}
+	if elapsed := time.Since(start); elapsed < interval {
+		t.Fatalf("Expected request to wait for the interval; took: %s", elapsed)
+	}
+
+	// number of servers lower than the number of responding servers is discovered again
+	sys.servers.observe([]ServerStatszResp{{Statsz: ServerStats{ActiveServers: 2}}})
+	if varz, err = sys.VarzPing(VarzEventOptions{}); err != nil {
+		t.Fatalf("Unable to fetch VARZ: %s", err)
+	}
+	if len(varz) != 2 {
+		t.Fatalf("Invalid number of responses: %d; want: %d", len(varz), 2)
+	}
+	time.Sleep(2 * interval)
+	if count := sys.servers.get(); count != -1 {
+		t.Fatalf("Expected server count to be reset; got: %d", count)
+	}
+	if varz, err = sys.VarzPing(VarzEventOptions{}); err != nil {
+		t.Fatalf("Unable to fetch VARZ: %s", err)
+	}
+	if len(varz) != 3 {
+		t.Fatalf("Invalid number of responses: %d; want: %d", len(varz), 3)
+	}
+	if count := sys.servers.get(); count != 3 {
+		t.Fatalf("Invalid server count; want: %d; got: %d", 3, count)
+	}
+}
+
+func TestAutoServerCountDiscovery(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	interval := 500 * time.Millisecond
+	sys, err := NewSysClient(sysConn, SysMultiRequestInterval(0, interval), AutoServerCount())
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	// number of servers is discovered with STATSZ before the first request
+	varz, err := sys.VarzPing(VarzEventOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch VARZ: %s", err)
+	}
+	if len(varz) != 3 {
+		t.Fatalf("Invalid number of responses: %d; want: %d", len(varz), 3)
+	}
+	if count := sys.servers.get(); count != 3 {
+		t.Fatalf("Invalid server count; want: %d; got: %d", 3, count)
+	}
+}
+
+func TestServerCounter(t *testing.T) {
+	counter := &serverCounter{ttl: 50 * time.Millisecond}
+	if count := counter.get(); count != -1 {
+		t.Fatalf("Invalid count; want: %d; got: %d", -1, count)
+	}
+
+	counter.observe([]ServerStatszResp{{Statsz: ServerStats{ActiveServers: 2}}, {Statsz: ServerStats{ActiveServers: 3}}})
+	if count := counter.get(); count != 3 {
+		t.Fatalf("Invalid count; want: %d; got: %d", 3, count)
+	}
+	// responses without active servers are ignored
+	counter.observe([]ServerStatszResp{{}})
+	if count := counter.get(); count != 3 {
+		t.Fatalf("Invalid count; want: %d; got: %d", 3, count)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if count := counter.get(); count != -1 {
+		t.Fatalf("Expected count to expire; got: %d", count)
+	}
+}
@@ -0,0 +1,166 @@
+package sys
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultSlowConsumerInterval = time.Second
+	DefaultSlowConsumerLimit    = 10
+)
+
+type (
+	// SlowConsumerReport lists servers on which the slow consumer counter increased,
+	// along with connections with the most pending data.
+	SlowConsumerReport struct {
+		Time    time.Time            `json:"time"`
+		Servers []SlowConsumerServer `json:"servers,omitempty"`
+	}
+
+	// SlowConsumerServer contains slow consumer information for a single server.
+	SlowConsumerServer struct {
+		Server        ServerInfo  `json:"server"`
+		SlowConsumers int64       `json:"slow_consumers"`
+		Increase      int64       `json:"increase"`
+		Suspects      []*ConnInfo `json:"suspects,omitempty"`
+	}
+
+	// SlowConsumerEvent is sent by WatchSlowConsumers after each poll which found new offenders
+	// or failed with an error.
+	SlowConsumerEvent struct {
+		Report *SlowConsumerReport
+		Err    error
+	}
+
+	// SlowConsumerOptions are options passed to FindSlowConsumers and WatchSlowConsumers
+	SlowConsumerOptions struct {
+		// Interval is the time between polls, DefaultSlowConsumerInterval by default.
+		Interval time.Duration
+
+		// Limit is the maximum number of suspect connections reported per server, DefaultSlowConsumerLimit by default.
+		Limit int
+
+		// MinPending is the number of pending bytes a connection has to exceed to be reported as a suspect.
+		MinPending int
+	}
+
+	slowConsumerTracker struct {
+		opts     SlowConsumerOptions
+		statsz   func() ([]ServerStatszResp, error)
+		connz    func(id string, opts ConnzEventOptions) (*ConnzResp, error)
+		counters map[string]int64
+		reported map[string]struct{}
+	}
+)
+
+// FindSlowConsumers polls STATSZ twice and reports servers on which the slow consumer counter increased
+// between the polls, with connections sorted by pending data as suspects.
+func (a *Analyzer) FindSlowConsumers(opts SlowConsumerOptions) (*SlowConsumerReport, error) {
+	tracker := a.newSlowConsumerTracker(opts)
+	if _, err := tracker.poll(); err != nil {
+		return nil, err
+	}
+	time.Sleep(tracker.opts.Interval)
+	return tracker.poll()
+}
+
+// WatchSlowConsumers keeps polling STATSZ until the context is done.
+// Only connections which were not reported before are included in subsequent reports.
+// Returned channel is closed when the context is done.
+func (a *Analyzer) WatchSlowConsumers(ctx context.Context, opts SlowConsumerOptions) <-chan SlowConsumerEvent {
+	tracker := a.newSlowConsumerTracker(opts)
+	events := make(chan SlowConsumerEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(tracker.opts.Interval)
+		defer ticker.Stop()
+		first := true
+		for {
+			report, err := tracker.poll()
+			if err != nil || (!first && len(report.Servers) > 0) {
+				select {
+				case events <- SlowConsumerEvent{Report: report, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err == nil {
+				first = false
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+func (a *Analyzer) newSlowConsumerTracker(opts SlowConsumerOptions) *slowConsumerTracker {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultSlowConsumerInterval
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultSlowConsumerLimit
+	}
+	return &slowConsumerTracker{
+		opts:     opts,
+		statsz:   func() ([]ServerStatszResp, error) { return a.client.ServerStatszPing(StatszEventOptions{}) },
+		connz:    a.client.Connz,
+		counters: make(map[string]int64),
+		reported: make(map[string]struct{}),
+	}
+}
+
+// poll fetches slow consumer counters and returns servers on which the counter increased since the previous poll.
+// Servers seen for the first time are only recorded.
+func (t *slowConsumerTracker) poll() (*SlowConsumerReport, error) {
+	statsz, err := t.statsz()
+	if err != nil {
+		return nil, err
+	}
+	report := &SlowConsumerReport{Time: time.Now().UTC()}
+	for _, resp := range statsz {
+		prev, ok := t.counters[resp.Server.ID]
+		t.counters[resp.Server.ID] = resp.Statsz.SlowConsumers
+		if !ok || resp.Statsz.SlowConsumers <= prev {
+			continue
+		}
+
+		server := SlowConsumerServer{
+			Server:        resp.Server,
+			SlowConsumers: resp.Statsz.SlowConsumers,
+			Increase:      resp.Statsz.SlowConsumers - prev,
+		}
+		connz, err := t.connz(resp.Server.ID, ConnzEventOptions{
+			ConnzOptions: ConnzOptions{
+				Sort:          ByPending,
+				Subscriptions: true,
+				Limit:         t.opts.Limit,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, conn := range connz.Connz.Conns {
+			if conn == nil || conn.Pending <= t.opts.MinPending {
+				continue
+			}
+			key := resp.Server.ID + "." + strconv.FormatUint(conn.Cid, 10)
+			if _, ok := t.reported[key]; ok {
+				continue
+			}
+			t.reported[key] = struct{}{}
+			server.Suspects = append(server.Suspects, conn)
+		}
+		report.Servers = append(report.Servers, server)
+	}
+	sort.Slice(report.Servers, func(i, j int) bool {
+		return report.Servers[i].Increase > report.Servers[j].Increase
+	})
+	return report, nil
+}
@@ -0,0 +1,129 @@
+package sys
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestFindSlowConsumers(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	report, err := sys.FindSlowConsumers(SlowConsumerOptions{Interval: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unable to find slow consumers: %s", err)
+	}
+	if len(report.Servers) != 0 {
+		t.Fatalf("Expected no slow consumers; got: %+v", report.Servers)
+	}
+}
+
+func TestSlowConsumerTracker(t *testing.T) {
+	counters := map[string]int64{"S1": 0, "S2": 3}
+	conns := map[string][]*ConnInfo{
+		"S1": {{Cid: 1, Pending: 1000}, {Cid: 2, Pending: 10}, {Cid: 3}},
+		"S2": {{Cid: 1, Pending: 500}},
+	}
+	tracker := &slowConsumerTracker{
+		opts: SlowConsumerOptions{Limit: 10, MinPending: 5},
+		statsz: func() ([]ServerStatszResp, error) {
+			resp := make([]ServerStatszResp, 0)
+			for _, id := range []string{"S1", "S2"} {
+				resp = append(resp, ServerStatszResp{Server: ServerInfo{ID: id}, Statsz: ServerStats{SlowConsumers: counters[id]}})
+			}
+			return resp, nil
+		},
+		connz: func(id string, opts ConnzEventOptions) (*ConnzResp, error) {
+			if opts.Sort != ByPending {
+				t.Fatalf("Invalid sort option: %s", opts.Sort)
+			}
+			return &ConnzResp{Server: ServerInfo{ID: id}, Connz: Connz{Conns: conns[id]}}, nil
+		},
+		counters: make(map[string]int64),
+		reported: make(map[string]struct{}),
+	}
+
+	report, err := tracker.poll()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(report.Servers) != 0 {
+		t.Fatalf("Expected no servers on first poll; got: %+v", report.Servers)
+	}
+
+	counters["S1"] = 2
+	report, err = tracker.poll()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(report.Servers) != 1 || report.Servers[0].Server.ID != "S1" || report.Servers[0].Increase != 2 {
+		t.Fatalf("Invalid slow consumer servers: %+v", report.Servers)
+	}
+	suspects := report.Servers[0].Suspects
+	if len(suspects) != 2 || suspects[0].Cid != 1 || suspects[1].Cid != 2 {
+		t.Fatalf("Invalid suspects: %+v", suspects)
+	}
+
+	// already reported connections are skipped
+	counters["S1"] = 3
+	conns["S1"] = append(conns["S1"], &ConnInfo{Cid: 4, Pending: 100})
+	report, err = tracker.poll()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(report.Servers) != 1 || len(report.Servers[0].Suspects) != 1 || report.Servers[0].Suspects[0].Cid != 4 {
+		t.Fatalf("Invalid slow consumer servers: %+v", report.Servers)
+	}
+}
+
+func TestWatchSlowConsumers(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	for event := range sys.WatchSlowConsumers(ctx, SlowConsumerOptions{Interval: 100 * time.Millisecond}) {
+		if event.Err != nil {
+			t.Fatalf("Unexpected error: %s", event.Err)
+		}
+		t.Fatalf("Expected no slow consumers; got: %+v", event.Report)
+	}
+}
@@ -0,0 +1,401 @@
+package sys
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// SnapshotVersion is the version of snapshot archive format.
+const SnapshotVersion = 1
+
+const (
+	snapshotManifestFile = "manifest.json"
+	snapshotVarzFile     = "varz.json"
+	snapshotConnzFile    = "connz.json"
+	snapshotSubszFile    = "subsz.json"
+	snapshotJszFile      = "jsz.json"
+	snapshotStatszFile   = "statsz.json"
+	snapshotHealthzFile  = "healthz.json"
+)
+
+var ErrSnapshotVersion = errors.New("unsupported snapshot version")
+
+type (
+	// Snapshot contains monitoring responses from all servers, captured at a single point in time.
+	// ExpectedServers is the number of servers expected to respond when the snapshot was captured, 0 if it was not known.
+	Snapshot struct {
+		Version         int                `json:"version"`
+		Time            time.Time          `json:"time"`
+		ExpectedServers int                `json:"expected_servers,omitempty"`
+		Varz            []VarzResp         `json:"varz"`
+		Connz           []ConnzResp        `json:"connz"`
+		Subsz           []SubszResp        `json:"subsz"`
+		Jsz             []JSZResp          `json:"jsz"`
+		Statsz          []ServerStatszResp `json:"statsz"`
+		Healthz         []HealthzResp      `json:"healthz"`
+	}
+
+	// SnapshotOptions are options used for requests made while capturing a snapshot.
+	// Details needed to replay any request with SnapshotSource are always captured:
+	// open and closed connections with usernames and subscriptions, all subscriptions
+	// and JetStream accounts with streams, consumers, configs and raft groups.
+	// The remaining options, e.g. event filters or an account, can be used to narrow the snapshot.
+	SnapshotOptions struct {
+		Varz    VarzEventOptions
+		Connz   ConnzEventOptions
+		Subsz   SubszOptions
+		Jsz     JszEventOptions
+		Statsz  StatszEventOptions
+		Healthz HealthzOptions
+	}
+
+	snapshotManifest struct {
+		Version         int       `json:"version"`
+		Time            time.Time `json:"time"`
+		ExpectedServers int       `json:"expected_servers,omitempty"`
+	}
+)
+
+// CaptureSnapshot requests VARZ, CONNZ, SUBSZ, JSZ, STATSZ and HEALTHZ from all servers.
+// Connections and subscriptions are paged through, so that the snapshot contains all of them.
+func (a *Analyzer) CaptureSnapshot(opts SnapshotOptions) (*Snapshot, error) {
+	var err error
+	snapshot := &Snapshot{
+		Version: SnapshotVersion,
+		Time:    time.Now().UTC(),
+	}
+	if provider, ok := a.client.(ServerCountProvider); ok {
+		snapshot.ExpectedServers = provider.ExpectedServers()
+	}
+
+	opts.Connz.State = ConnAll
+	opts.Connz.Username = true
+	opts.Connz.Subscriptions, opts.Connz.SubscriptionsDetail = false, true
+	opts.Subsz.Subscriptions = true
+	opts.Jsz.Accounts = true
+	opts.Jsz.Streams = true
+	opts.Jsz.Consumer = true
+	opts.Jsz.Config = true
+	opts.Jsz.RaftGroups = true
+	opts.Jsz.LeaderOnly = false
+	opts.Jsz.Offset, opts.Jsz.Limit = 0, math.MaxInt32
+
+	if snapshot.Varz, err = a.client.VarzPing(opts.Varz); err != nil {
+		return nil, err
+	}
+	if snapshot.Connz, err = connzAll(a.client, opts.Connz); err != nil {
+		return nil, err
+	}
+	if snapshot.Subsz, err = subszAll(a.client, opts.Subsz); err != nil {
+		return nil, err
+	}
+	if snapshot.Jsz, err = a.client.JszPing(opts.Jsz); err != nil {
+		return nil, err
+	}
+	if snapshot.Statsz, err = a.client.ServerStatszPing(opts.Statsz); err != nil {
+		return nil, err
+	}
+	if snapshot.Healthz, err = a.client.HealthzPing(opts.Healthz); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Write writes the snapshot to w as a gzip compressed tar archive, with a JSON file per endpoint.
+func (snap *Snapshot) Write(w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{snapshotManifestFile, snapshotManifest{Version: snap.Version, Time: snap.Time, ExpectedServers: snap.ExpectedServers}},
+		{snapshotVarzFile, snap.Varz},
+		{snapshotConnzFile, snap.Connz},
+		{snapshotSubszFile, snap.Subsz},
+		{snapshotJszFile, snap.Jsz},
+		{snapshotStatszFile, snap.Statsz},
+		{snapshotHealthzFile, snap.Healthz},
+	}
+	for _, file := range files {
+		data, err := json.Marshal(file.data)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    file.name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: snap.Time,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// ReadSnapshot reads a snapshot archive created with Snapshot.Write.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	snapshot := &Snapshot{}
+	var manifest *snapshotManifest
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var target interface{}
+		switch hdr.Name {
+		case snapshotManifestFile:
+			manifest = &snapshotManifest{}
+			target = manifest
+		case snapshotVarzFile:
+			target = &snapshot.Varz
+		case snapshotConnzFile:
+			target = &snapshot.Connz
+		case snapshotSubszFile:
+			target = &snapshot.Subsz
+		case snapshotJszFile:
+			target = &snapshot.Jsz
+		case snapshotStatszFile:
+			target = &snapshot.Statsz
+		case snapshotHealthzFile:
+			target = &snapshot.Healthz
+		default:
+			continue
+		}
+		if err := json.NewDecoder(tr).Decode(target); err != nil {
+			return nil, fmt.Errorf("reading %q: %w", hdr.Name, err)
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrValidation, snapshotManifestFile)
+	}
+	if manifest.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, manifest.Version)
+	}
+	snapshot.Version = manifest.Version
+	snapshot.Time = manifest.Time
+	snapshot.ExpectedServers = manifest.ExpectedServers
+
+	return snapshot, nil
+}
+
+// SnapshotSource serves monitoring responses from a captured snapshot,
+// using the same methods as System.
+// Request options (e.g. connection state, account and subject filters, sorting and pagination)
+// are applied to captured responses the same way servers apply them, so the snapshot should contain
+// all details, see CaptureSnapshot. ErrSnapshotReplay is returned if the snapshot is missing details
+// required by the request.
+type SnapshotSource struct {
+	snapshot *Snapshot
+}
+
+func NewSnapshotSource(snapshot *Snapshot) *SnapshotSource {
+	return &SnapshotSource{snapshot: snapshot}
+}
+
+// ExpectedServers returns the number of servers expected to respond when the snapshot was captured.
+func (ss *SnapshotSource) ExpectedServers() int {
+	return ss.snapshot.ExpectedServers
+}
+
+// Snapshot returns the underlying snapshot
+func (ss *SnapshotSource) Snapshot() *Snapshot {
+	return ss.snapshot
+}
+
+func (ss *SnapshotSource) Varz(id string, _ VarzEventOptions) (*VarzResp, error) {
+	for i, resp := range ss.snapshot.Varz {
+		if resp.Server.ID == id {
+			return &ss.snapshot.Varz[i], nil
+		}
+	}
+	return nil, snapshotServerErr(id)
+}
+
+func (ss *SnapshotSource) VarzPing(opts VarzEventOptions) ([]VarzResp, error) {
+	res := make([]VarzResp, 0, len(ss.snapshot.Varz))
+	for _, resp := range ss.snapshot.Varz {
+		if matchesFilter(resp.Server, opts.EventFilterOptions) {
+			res = append(res, resp)
+		}
+	}
+	return res, nil
+}
+
+func (ss *SnapshotSource) Connz(id string, opts ConnzEventOptions) (*ConnzResp, error) {
+	for _, resp := range ss.snapshot.Connz {
+		if resp.Server.ID == id {
+			return replayConnz(resp, opts.ConnzOptions)
+		}
+	}
+	return nil, snapshotServerErr(id)
+}
+
+func (ss *SnapshotSource) ConnzPing(opts ConnzEventOptions) ([]ConnzResp, error) {
+	res := make([]ConnzResp, 0, len(ss.snapshot.Connz))
+	for _, resp := range ss.snapshot.Connz {
+		if !matchesFilter(resp.Server, opts.EventFilterOptions) {
+			continue
+		}
+		replayed, err := replayConnz(resp, opts.ConnzOptions)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *replayed)
+	}
+	return res, nil
+}
+
+func (ss *SnapshotSource) ServerSubsz(id string, opts SubszOptions) (*SubszResp, error) {
+	for _, resp := range ss.snapshot.Subsz {
+		if resp.Server.ID == id {
+			return replaySubsz(resp, opts)
+		}
+	}
+	return nil, snapshotServerErr(id)
+}
+
+func (ss *SnapshotSource) ServerSubszPing(opts SubszOptions) ([]SubszResp, error) {
+	res := make([]SubszResp, 0, len(ss.snapshot.Subsz))
+	for _, resp := range ss.snapshot.Subsz {
+		replayed, err := replaySubsz(resp, opts)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *replayed)
+	}
+	return res, nil
+}
+
+func (ss *SnapshotSource) Jsz(id string, opts JszEventOptions) (*JSZResp, error) {
+	for _, resp := range ss.snapshot.Jsz {
+		if resp.Server.ID == id {
+			replayed, ok := replayJsz(resp, opts.JszOptions, metaLeader(ss.snapshot.Jsz))
+			if !ok {
+				// the server does not respond, as it is not the meta leader
+				return nil, nats.ErrTimeout
+			}
+			return replayed, nil
+		}
+	}
+	return nil, snapshotServerErr(id)
+}
+
+func (ss *SnapshotSource) JszPing(opts JszEventOptions) ([]JSZResp, error) {
+	leader := metaLeader(ss.snapshot.Jsz)
+	res := make([]JSZResp, 0, len(ss.snapshot.Jsz))
+	for _, resp := range ss.snapshot.Jsz {
+		if !matchesFilter(resp.Server, opts.EventFilterOptions) {
+			continue
+		}
+		if replayed, ok := replayJsz(resp, opts.JszOptions, leader); ok {
+			res = append(res, *replayed)
+		}
+	}
+	return res, nil
+}
+
+func (ss *SnapshotSource) ServerStatsz(id string, _ StatszEventOptions) (*ServerStatszResp, error) {
+	for i, resp := range ss.snapshot.Statsz {
+		if resp.Server.ID == id {
+			return &ss.snapshot.Statsz[i], nil
+		}
+	}
+	return nil, snapshotServerErr(id)
+}
+
+func (ss *SnapshotSource) ServerStatszPing(opts StatszEventOptions) ([]ServerStatszResp, error) {
+	res := make([]ServerStatszResp, 0, len(ss.snapshot.Statsz))
+	for _, resp := range ss.snapshot.Statsz {
+		if matchesFilter(resp.Server, opts.EventFilterOptions) {
+			res = append(res, resp)
+		}
+	}
+	return res, nil
+}
+
+func (ss *SnapshotSource) Healthz(id string, opts HealthzOptions) (*HealthzResp, error) {
+	if opts != (HealthzOptions{}) {
+		return nil, fmt.Errorf("%w: HEALTHZ options are not supported", ErrSnapshotReplay)
+	}
+	for i, resp := range ss.snapshot.Healthz {
+		if resp.Server.ID == id {
+			return &ss.snapshot.Healthz[i], nil
+		}
+	}
+	return nil, snapshotServerErr(id)
+}
+
+func (ss *SnapshotSource) HealthzPing(opts HealthzOptions) ([]HealthzResp, error) {
+	if opts != (HealthzOptions{}) {
+		return nil, fmt.Errorf("%w: HEALTHZ options are not supported", ErrSnapshotReplay)
+	}
+	res := make([]HealthzResp, len(ss.snapshot.Healthz))
+	copy(res, ss.snapshot.Healthz)
+	return res, nil
+}
+
+func snapshotServerErr(id string) error {
+	if id == "" {
+		return fmt.Errorf("%w: server id cannot be empty", ErrValidation)
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidServerID, id)
+}
+
+// matchesFilter applies server filters the same way the server does for PING requests.
+func matchesFilter(info ServerInfo, filter EventFilterOptions) bool {
+	if filter.Name != "" && !strings.Contains(info.Name, filter.Name) {
+		return false
+	}
+	if filter.Cluster != "" && !strings.Contains(info.Cluster, filter.Cluster) {
+		return false
+	}
+	if filter.Host != "" && !strings.Contains(info.Host, filter.Host) {
+		return false
+	}
+	if filter.Domain != "" && filter.Domain != info.Domain {
+		return false
+	}
+	for _, tag := range filter.Tags {
+		var found bool
+		for _, serverTag := range info.Tags {
+			if strings.EqualFold(tag, serverTag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,266 @@
+package sys
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultReplayLimit is the number of connections and subscriptions returned by servers if limit is not set.
+const defaultReplayLimit = 1024
+
+// ErrSnapshotReplay is returned by SnapshotSource for request options which cannot be applied to captured responses.
+var ErrSnapshotReplay = errors.New("request cannot be replayed from snapshot")
+
+// replayConnz applies CONNZ options to a captured response, the same way the server does.
+func replayConnz(resp ConnzResp, opts ConnzOptions) (*ConnzResp, error) {
+	if (opts.Sort == ByStop || opts.Sort == ByReason) && opts.State == ConnOpen {
+		return nil, fmt.Errorf("%w: sorting by %s is only valid for closed connections", ErrValidation, opts.Sort)
+	}
+	less, err := connLess(opts.Sort, resp.Connz.Now)
+	if err != nil {
+		return nil, err
+	}
+	// filtering by subject is only supported when filtering by account
+	filterSubject := opts.FilterSubject
+	if opts.Account == "" {
+		filterSubject = ""
+	}
+
+	conns := make([]*ConnInfo, 0, len(resp.Connz.Conns))
+	for _, conn := range resp.Connz.Conns {
+		if conn == nil {
+			continue
+		}
+		switch {
+		case opts.State == ConnOpen && conn.Stop != nil,
+			opts.State == ConnClosed && conn.Stop == nil,
+			opts.CID != 0 && conn.Cid != opts.CID,
+			opts.MQTTClient != "" && conn.MQTTClient != opts.MQTTClient,
+			opts.User != "" && conn.AuthorizedUser != opts.User,
+			opts.Account != "" && conn.Account != opts.Account:
+			continue
+		}
+		if filterSubject != "" {
+			matches, err := connSubscribes(conn, filterSubject)
+			if err != nil {
+				return nil, err
+			}
+			if !matches {
+				continue
+			}
+		}
+		if (opts.Subscriptions || opts.SubscriptionsDetail) && conn.NumSubs > 0 && len(conn.SubsDetail) == 0 {
+			return nil, fmt.Errorf("%w: subscriptions of connection %d were not captured", ErrSnapshotReplay, conn.Cid)
+		}
+
+		replayed := *conn
+		if !opts.Username {
+			replayed.AuthorizedUser = ""
+		}
+		replayed.Subs, replayed.SubsDetail = nil, nil
+		if opts.SubscriptionsDetail {
+			replayed.SubsDetail = conn.SubsDetail
+		} else if opts.Subscriptions {
+			replayed.Subs = make([]string, 0, len(conn.SubsDetail))
+			for _, sub := range conn.SubsDetail {
+				replayed.Subs = append(replayed.Subs, sub.Subject)
+			}
+		}
+		conns = append(conns, &replayed)
+	}
+	sort.SliceStable(conns, func(i, j int) bool { return less(conns[i], conns[j]) })
+
+	offset, limit := replayPage(opts.Offset, opts.Limit)
+	res := resp
+	res.Connz.Total = len(conns)
+	res.Connz.Offset = offset
+	res.Connz.Limit = limit
+	res.Connz.Conns = page(conns, offset, limit)
+	res.Connz.NumConns = len(res.Connz.Conns)
+	return &res, nil
+}
+
+// connSubscribes checks whether the connection has a subscription colliding with the subject.
+func connSubscribes(conn *ConnInfo, subject string) (bool, error) {
+	if conn.NumSubs > 0 && len(conn.SubsDetail) == 0 && len(conn.Subs) == 0 {
+		return false, fmt.Errorf("%w: subscriptions of connection %d were not captured", ErrSnapshotReplay, conn.Cid)
+	}
+	for _, sub := range conn.SubsDetail {
+		if subjectsCollide(sub.Subject, subject) {
+			return true, nil
+		}
+	}
+	for _, sub := range conn.Subs {
+		if subjectsCollide(sub, subject) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// connLess returns the ordering of connections for given sort option.
+// Only sorting by connection ID is ascending, all others are descending.
+func connLess(sortOpt SortOpt, now time.Time) (func(a, b *ConnInfo) bool, error) {
+	end := func(c *ConnInfo) time.Time {
+		if c.Stop != nil {
+			return *c.Stop
+		}
+		return now
+	}
+	switch sortOpt {
+	case "", ByCid, ByStart:
+		return func(a, b *ConnInfo) bool { return a.Cid < b.Cid }, nil
+	case BySubs:
+		return func(a, b *ConnInfo) bool { return a.NumSubs > b.NumSubs }, nil
+	case ByPending:
+		return func(a, b *ConnInfo) bool { return a.Pending > b.Pending }, nil
+	case ByOutMsgs:
+		return func(a, b *ConnInfo) bool { return a.OutMsgs > b.OutMsgs }, nil
+	case ByInMsgs:
+		return func(a, b *ConnInfo) bool { return a.InMsgs > b.InMsgs }, nil
+	case ByOutBytes:
+		return func(a, b *ConnInfo) bool { return a.OutBytes > b.OutBytes }, nil
+	case ByInBytes:
+		return func(a, b *ConnInfo) bool { return a.InBytes > b.InBytes }, nil
+	case ByLast:
+		return func(a, b *ConnInfo) bool { return a.LastActivity.After(b.LastActivity) }, nil
+	case ByIdle:
+		return func(a, b *ConnInfo) bool { return end(a).Sub(a.LastActivity) > end(b).Sub(b.LastActivity) }, nil
+	case ByUptime:
+		return func(a, b *ConnInfo) bool { return end(a).Sub(a.Start) > end(b).Sub(b.Start) }, nil
+	case ByStop:
+		return func(a, b *ConnInfo) bool { return end(a).After(end(b)) }, nil
+	case ByReason:
+		return func(a, b *ConnInfo) bool { return a.Reason > b.Reason }, nil
+	}
+	return nil, fmt.Errorf("%w: invalid sort option: %q", ErrValidation, sortOpt)
+}
+
+// replaySubsz applies SUBSZ options to a captured response, the same way the server does.
+func replaySubsz(resp SubszResp, opts SubszOptions) (*SubszResp, error) {
+	if opts.Test != "" && !isLiteralSubject(opts.Test) {
+		return nil, fmt.Errorf("%w: test subject has to be a literal publish subject: %q", ErrValidation, opts.Test)
+	}
+	res := resp
+	res.Subsz.Subs = nil
+	if !opts.Subscriptions {
+		return &res, nil
+	}
+	if resp.Subsz.SublistStats != nil && resp.Subsz.NumSubs > 0 && len(resp.Subsz.Subs) == 0 {
+		return nil, fmt.Errorf("%w: subscriptions of server %s were not captured", ErrSnapshotReplay, resp.Server.ID)
+	}
+
+	subs := make([]SubDetail, 0, len(resp.Subsz.Subs))
+	for _, sub := range resp.Subsz.Subs {
+		if opts.Account != "" && sub.Account != opts.Account {
+			continue
+		}
+		if opts.Test != "" && !subjectsCollide(sub.Subject, opts.Test) {
+			continue
+		}
+		subs = append(subs, sub)
+	}
+	offset, limit := replayPage(opts.Offset, opts.Limit)
+	res.Subsz.Total = len(subs)
+	res.Subsz.Offset = offset
+	res.Subsz.Limit = limit
+	res.Subsz.Subs = page(subs, offset, limit)
+	return &res, nil
+}
+
+// replayJsz applies JSZ options to a captured response, the same way the server does.
+// False is returned if the server would not respond, i.e. for leader only requests sent to servers other than the meta leader.
+func replayJsz(resp JSZResp, opts JszOptions, leader *JSZResp) (*JSZResp, bool) {
+	if opts.LeaderOnly && (resp.JSInfo.Disabled || (resp.JSInfo.Meta != nil && (leader == nil || leader.Server.ID != resp.Server.ID))) {
+		return nil, false
+	}
+	if opts.Consumer {
+		opts.Streams = true
+	}
+	if opts.Streams {
+		opts.Accounts = true
+	}
+
+	res := resp
+	res.JSInfo.AccountDetails = nil
+	accounts := make([]*AccountDetail, 0, len(resp.JSInfo.AccountDetails))
+	for _, acc := range resp.JSInfo.AccountDetails {
+		if acc != nil && acc.Name == opts.Account {
+			accounts = []*AccountDetail{acc}
+			break
+		}
+		if acc != nil && opts.Accounts {
+			accounts = append(accounts, acc)
+		}
+	}
+	// same as the server, all accounts are returned if the account filter does not match
+	if len(accounts) != 1 || accounts[0].Name != opts.Account {
+		sort.Slice(accounts, func(i, j int) bool { return accounts[i].Name < accounts[j].Name })
+		offset, limit := replayPage(opts.Offset, opts.Limit)
+		accounts = page(accounts, offset, limit)
+	}
+	if len(accounts) == 0 {
+		return &res, true
+	}
+
+	res.JSInfo.AccountDetails = make([]*AccountDetail, 0, len(accounts))
+	for _, acc := range accounts {
+		detail := *acc
+		detail.Streams = nil
+		if opts.Streams {
+			detail.Streams = make([]StreamDetail, 0, len(acc.Streams))
+			for _, stream := range acc.Streams {
+				if !opts.Consumer {
+					stream.Consumer = nil
+				}
+				if !opts.Config {
+					stream.Config = nil
+				}
+				if !opts.RaftGroups {
+					stream.RaftGroup, stream.ConsumerRaftGroups = "", nil
+				}
+				detail.Streams = append(detail.Streams, stream)
+			}
+		}
+		res.JSInfo.AccountDetails = append(res.JSInfo.AccountDetails, &detail)
+	}
+	return &res, true
+}
+
+func replayPage(offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = defaultReplayLimit
+	}
+	return offset, limit
+}
+
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return items[:0]
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
+
+// subjectsCollide checks whether two subjects, possibly containing wildcards, match a common literal subject.
+func subjectsCollide(a, b string) bool {
+	at, bt := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(at) && i < len(bt); i++ {
+		if at[i] == ">" || bt[i] == ">" {
+			return true
+		}
+		if at[i] != bt[i] && at[i] != "*" && bt[i] != "*" {
+			return false
+		}
+	}
+	return len(at) == len(bt)
+}
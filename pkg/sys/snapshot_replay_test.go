@@ -0,0 +1,224 @@
+package sys
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestSnapshotSourceReplayConnz(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	stop := now.Add(-time.Minute)
+	source := NewSnapshotSource(&Snapshot{Connz: []ConnzResp{{
+		Server: ServerInfo{ID: "S1", Name: "s1"},
+		Connz: Connz{Now: now, Conns: []*ConnInfo{
+			{Cid: 1, Account: "A", AuthorizedUser: "u1", InMsgs: 5, NumSubs: 1, SubsDetail: []SubDetail{{Subject: "foo", Cid: 1}}},
+			{Cid: 2, Account: "A", InMsgs: 10, NumSubs: 1, SubsDetail: []SubDetail{{Subject: "bar.*", Cid: 2}}},
+			{Cid: 3, Account: "B", InMsgs: 1, LastActivity: stop.Add(-time.Second)},
+			{Cid: 4, Account: "A", Stop: &stop, Reason: "Client Closed"},
+		}},
+	}}})
+
+	tests := []struct {
+		name      string
+		opts      ConnzOptions
+		expected  []uint64
+		total     int
+		withError error
+	}{
+		{name: "open connections by default", expected: []uint64{1, 2, 3}, total: 3},
+		{name: "closed connections", opts: ConnzOptions{State: ConnClosed}, expected: []uint64{4}, total: 1},
+		{name: "all connections", opts: ConnzOptions{State: ConnAll}, expected: []uint64{1, 2, 3, 4}, total: 4},
+		{name: "by account", opts: ConnzOptions{Account: "A"}, expected: []uint64{1, 2}, total: 2},
+		{name: "by account and subject", opts: ConnzOptions{Account: "A", FilterSubject: "bar.baz"}, expected: []uint64{2}, total: 1},
+		{name: "subject filter requires account", opts: ConnzOptions{FilterSubject: "bar.baz"}, expected: []uint64{1, 2, 3}, total: 3},
+		{name: "by user", opts: ConnzOptions{User: "u1"}, expected: []uint64{1}, total: 1},
+		{name: "by cid", opts: ConnzOptions{CID: 3}, expected: []uint64{3}, total: 1},
+		{name: "sorted with pagination", opts: ConnzOptions{Sort: ByInMsgs, Offset: 1, Limit: 1}, expected: []uint64{1}, total: 3},
+		{name: "offset out of range", opts: ConnzOptions{Offset: 5}, expected: []uint64{}, total: 3},
+		{name: "sort by stop requires closed connections", opts: ConnzOptions{Sort: ByStop}, withError: ErrValidation},
+		{name: "invalid sort", opts: ConnzOptions{Sort: "asd"}, withError: ErrValidation},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := source.ConnzPing(ConnzEventOptions{ConnzOptions: test.opts})
+			if test.withError != nil {
+				if !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error: %s; got: %v", test.withError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			cids := make([]uint64, 0)
+			for _, conn := range resp[0].Connz.Conns {
+				cids = append(cids, conn.Cid)
+			}
+			if !reflect.DeepEqual(cids, test.expected) {
+				t.Fatalf("Invalid connections; want: %v; got: %v", test.expected, cids)
+			}
+			if resp[0].Connz.Total != test.total || resp[0].Connz.NumConns != len(test.expected) {
+				t.Fatalf("Invalid totals; want: %d; got: %d (%d)", test.total, resp[0].Connz.Total, resp[0].Connz.NumConns)
+			}
+		})
+	}
+
+	resp, err := source.Connz("S1", ConnzEventOptions{ConnzOptions: ConnzOptions{CID: 1, Subscriptions: true}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if conn := resp.Connz.Conns[0]; conn.AuthorizedUser != "" || conn.SubsDetail != nil || !reflect.DeepEqual(conn.Subs, []string{"foo"}) {
+		t.Fatalf("Invalid connection details: %+v", conn)
+	}
+	if _, err := NewSnapshotSource(&Snapshot{Connz: []ConnzResp{{Connz: Connz{Conns: []*ConnInfo{{Cid: 1, NumSubs: 1}}}}}}).
+		ConnzPing(ConnzEventOptions{ConnzOptions: ConnzOptions{SubscriptionsDetail: true}}); !errors.Is(err, ErrSnapshotReplay) {
+		t.Fatalf("Expected error: %s; got: %v", ErrSnapshotReplay, err)
+	}
+}
+
+func TestSnapshotSourceReplaySubsz(t *testing.T) {
+	source := NewSnapshotSource(&Snapshot{Subsz: []SubszResp{{
+		Server: ServerInfo{ID: "S1"},
+		Subsz: Subsz{Subs: []SubDetail{
+			{Account: "A", Subject: "foo", Cid: 1},
+			{Account: "A", Subject: "bar", Cid: 1},
+			{Account: "A", Subject: "*", Cid: 2},
+			{Account: "B", Subject: "foo", Cid: 3},
+		}},
+	}}})
+
+	tests := []struct {
+		name     string
+		opts     SubszOptions
+		expected []uint64
+	}{
+		{name: "without subscriptions", opts: SubszOptions{}, expected: []uint64{}},
+		{name: "all subscriptions", opts: SubszOptions{Subscriptions: true}, expected: []uint64{1, 1, 2, 3}},
+		{name: "by account and test subject", opts: SubszOptions{Subscriptions: true, Account: "A", Test: "foo"}, expected: []uint64{1, 2}},
+		{name: "with limit", opts: SubszOptions{Subscriptions: true, Offset: 1, Limit: 2}, expected: []uint64{1, 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := source.ServerSubszPing(test.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			cids := make([]uint64, 0)
+			for _, sub := range resp[0].Subsz.Subs {
+				cids = append(cids, sub.Cid)
+			}
+			if !reflect.DeepEqual(cids, test.expected) {
+				t.Fatalf("Invalid subscriptions; want: %v; got: %v", test.expected, cids)
+			}
+		})
+	}
+
+	if _, err := source.ServerSubsz("S1", SubszOptions{Subscriptions: true, Test: "foo.*"}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected error: %s; got: %v", ErrValidation, err)
+	}
+}
+
+func TestSnapshotSourceReplayJsz(t *testing.T) {
+	jsz := func(name, leader string) JSZResp {
+		return JSZResp{
+			Server: ServerInfo{ID: name, Name: name},
+			JSInfo: JSInfo{
+				Meta: &MetaClusterInfo{Leader: leader},
+				AccountDetails: []*AccountDetail{
+					{Name: "B"},
+					{Name: "A", Streams: []StreamDetail{{
+						Name:      "ORDERS",
+						Config:    &nats.StreamConfig{Name: "ORDERS"},
+						RaftGroup: "S-R3F-1",
+						Consumer:  []*nats.ConsumerInfo{{Name: "c1"}},
+					}}},
+				},
+			},
+		}
+	}
+	source := NewSnapshotSource(&Snapshot{Jsz: []JSZResp{jsz("s1", "s2"), jsz("s2", "s2")}})
+
+	resp, err := source.JszPing(JszEventOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(resp) != 2 || resp[0].JSInfo.AccountDetails != nil {
+		t.Fatalf("Expected account details to be omitted: %+v", resp)
+	}
+
+	resp, err = source.JszPing(JszEventOptions{JszOptions: JszOptions{Account: "A", Streams: true, LeaderOnly: true}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(resp) != 1 || resp[0].Server.Name != "s2" || len(resp[0].JSInfo.AccountDetails) != 1 {
+		t.Fatalf("Invalid leader only response: %+v", resp)
+	}
+	stream := resp[0].JSInfo.AccountDetails[0].Streams[0]
+	if stream.Name != "ORDERS" || stream.Config != nil || stream.RaftGroup != "" || stream.Consumer != nil {
+		t.Fatalf("Invalid stream details: %+v", stream)
+	}
+
+	resp, err = source.JszPing(JszEventOptions{JszOptions: JszOptions{Consumer: true, Limit: 1}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if accounts := resp[0].JSInfo.AccountDetails; len(accounts) != 1 || accounts[0].Name != "A" || len(accounts[0].Streams[0].Consumer) != 1 {
+		t.Fatalf("Invalid account details: %+v", accounts)
+	}
+
+	// same as the server, all accounts are returned if the account is not found
+	resp, err = source.JszPing(JszEventOptions{JszOptions: JszOptions{Account: "C", Accounts: true}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if accounts := resp[0].JSInfo.AccountDetails; len(accounts) != 2 || accounts[0].Streams != nil {
+		t.Fatalf("Invalid account details: %+v", accounts)
+	}
+
+	if _, err := source.Jsz("s1", JszEventOptions{JszOptions: JszOptions{LeaderOnly: true}}); !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("Expected error: %s; got: %v", nats.ErrTimeout, err)
+	}
+	if _, err := source.HealthzPing(HealthzOptions{JSEnabledOnly: true}); !errors.Is(err, ErrSnapshotReplay) {
+		t.Fatalf("Expected error: %s; got: %v", ErrSnapshotReplay, err)
+	}
+}
+
+func TestAnalyzerSnapshotFilters(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	source := NewSnapshotSource(&Snapshot{
+		Connz: []ConnzResp{{
+			Server: ServerInfo{ID: "S1", Name: "s1"},
+			Connz: Connz{Now: now, Conns: []*ConnInfo{
+				{Cid: 1, Account: "A", LastActivity: now, NumSubs: 1, SubsDetail: []SubDetail{{Account: "A", Subject: "foo", Cid: 1}}},
+				{Cid: 2, Account: "A", LastActivity: now, NumSubs: 1, SubsDetail: []SubDetail{{Account: "A", Subject: "bar", Cid: 2}}},
+			}},
+		}},
+		Subsz: []SubszResp{{
+			Server: ServerInfo{ID: "S1", Name: "s1"},
+			Subsz:  Subsz{Subs: []SubDetail{{Account: "A", Subject: "foo", Cid: 1}, {Account: "A", Subject: "bar", Cid: 2}}},
+		}},
+	})
+	analyzer := NewAnalyzer(source)
+
+	disconnects, err := analyzer.RecentDisconnects(DisconnectOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if disconnects.Total != 0 {
+		t.Fatalf("Expected open connections not to be reported as disconnects: %+v", disconnects)
+	}
+
+	interest, err := analyzer.WhoReceives("A", "foo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(interest.Subscriptions) != 1 || interest.Subscriptions[0].Subscription.Subject != "foo" {
+		t.Fatalf("Invalid subscriptions: %+v", interest.Subscriptions)
+	}
+}
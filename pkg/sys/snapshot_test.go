@@ -0,0 +1,97 @@
+package sys
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestSnapshot(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	snapshot, err := sys.CaptureSnapshot(SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("Unable to capture snapshot: %s", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := snapshot.Write(buf); err != nil {
+		t.Fatalf("Unable to write snapshot: %s", err)
+	}
+	restored, err := ReadSnapshot(buf)
+	if err != nil {
+		t.Fatalf("Unable to read snapshot: %s", err)
+	}
+	if restored.Version != SnapshotVersion || !restored.Time.Equal(snapshot.Time) {
+		t.Fatalf("Invalid snapshot header: %d %s", restored.Version, restored.Time)
+	}
+	for name, count := range map[string]int{
+		"varz":    len(restored.Varz),
+		"connz":   len(restored.Connz),
+		"subsz":   len(restored.Subsz),
+		"jsz":     len(restored.Jsz),
+		"statsz":  len(restored.Statsz),
+		"healthz": len(restored.Healthz),
+	} {
+		if count != 3 {
+			t.Fatalf("Invalid number of %s responses: %d; want: %d", name, count, 3)
+		}
+	}
+
+	source := NewSnapshotSource(restored)
+	id := c.servers[1].ID()
+	varz, err := source.Varz(id, VarzEventOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch VARZ: %s", err)
+	}
+	if varz.Varz.ID != id {
+		t.Fatalf("Invalid server varz response: %+v", varz)
+	}
+	if _, err := source.Jsz("asd", JszEventOptions{}); !errors.Is(err, ErrInvalidServerID) {
+		t.Fatalf("Expected error; want: %s; got: %s", ErrInvalidServerID, err)
+	}
+	if _, err := source.Healthz("", HealthzOptions{}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected error; want: %s; got: %s", ErrValidation, err)
+	}
+
+	resp, err := source.ConnzPing(ConnzEventOptions{EventFilterOptions: EventFilterOptions{Name: c.servers[2].Name()}})
+	if err != nil {
+		t.Fatalf("Unable to fetch CONNZ: %s", err)
+	}
+	if len(resp) != 1 || resp[0].Server.Name != c.servers[2].Name() {
+		t.Fatalf("Invalid filtered CONNZ response: %+v", resp)
+	}
+}
+
+func TestReadSnapshotInvalidVersion(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := (&Snapshot{Version: SnapshotVersion + 1}).Write(buf); err != nil {
+		t.Fatalf("Unable to write snapshot: %s", err)
+	}
+	if _, err := ReadSnapshot(buf); !errors.Is(err, ErrSnapshotVersion) {
+		t.Fatalf("Expected error; want: %s; got: %s", ErrSnapshotVersion, err)
+	}
+}
@@ -69,13 +69,12 @@ func (s *System) ServerStatsz(id string, opts StatszEventOptions) (*ServerStatsz
 	if id == "" {
 		return nil, fmt.Errorf("%w: server id cannot be empty", ErrValidation)
 	}
-	conn := s.nc
 	subj := fmt.Sprintf(srvStatszSubj, id)
 	payload, err := json.Marshal(opts)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := conn.Request(subj, payload, s.opts.timeout)
+	resp, err := s.request(subj, payload)
 	if err != nil {
 		if errors.Is(err, nats.ErrNoResponders) {
 			return nil, fmt.Errorf("%w: %s", ErrInvalidServerID, id)
@@ -109,5 +108,6 @@ func (s *System) ServerStatszPing(opts StatszEventOptions) ([]ServerStatszResp,
 		}
 		srvStatsz = append(srvStatsz, statszResp)
 	}
+	s.servers.observe(srvStatsz)
 	return srvStatsz, nil
 }
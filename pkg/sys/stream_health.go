@@ -0,0 +1,260 @@
+package sys
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	DefaultStreamHealthSamples        = 2
+	DefaultStreamHealthSampleInterval = 500 * time.Millisecond
+)
+
+type (
+	// StreamHealthReport contains health information for all JetStream streams in the cluster.
+	StreamHealthReport struct {
+		Status  HealthStatus   `json:"status"`
+		Streams []StreamHealth `json:"streams,omitempty"`
+	}
+
+	// StreamHealth describes replica health of a single stream.
+	StreamHealth struct {
+		Account  string          `json:"account"`
+		Name     string          `json:"name"`
+		Leader   string          `json:"leader,omitempty"`
+		Replicas int             `json:"replicas"`
+		Peers    int             `json:"peers"`
+		Status   HealthStatus    `json:"status"`
+		Findings []HealthFinding `json:"findings,omitempty"`
+	}
+
+	// StreamHealthOptions are options passed to StreamHealth
+	StreamHealthOptions struct {
+		// Account limits the report to streams from a single account.
+		Account string
+
+		// MaxReplicaLag is the number of operations a stream replica can lag behind the leader
+		// before being reported. If not set, only replicas the server reports as not current are reported.
+		MaxReplicaLag uint64
+
+		// Samples is the number of JSZ samples taken to detect consumers with growing pending count,
+		// DefaultStreamHealthSamples by default. Setting it to 1 disables the check.
+		Samples int
+
+		// SampleInterval is the time between consecutive samples, DefaultStreamHealthSampleInterval by default.
+		SampleInterval time.Duration
+	}
+
+	streamKey struct {
+		account string
+		name    string
+	}
+)
+
+// StreamHealth reports streams with no leader, lagging or offline replicas, fewer peers than configured replicas
+// and consumers with constantly growing number of pending messages.
+// Streams reported by multiple servers are de-duplicated, preferring the stream leader's view.
+func (a *Analyzer) StreamHealth(opts StreamHealthOptions) (*StreamHealthReport, error) {
+	jszOpts := JszEventOptions{
+		JszOptions: JszOptions{
+			Account:    opts.Account,
+			Accounts:   true,
+			Streams:    true,
+			Consumer:   true,
+			Config:     true,
+			RaftGroups: true,
+		},
+	}
+	interval := opts.SampleInterval
+	if interval <= 0 {
+		interval = DefaultStreamHealthSampleInterval
+	}
+	samples := opts.Samples
+	if samples <= 0 {
+		samples = DefaultStreamHealthSamples
+	}
+
+	jsz := make([][]JSZResp, 0, samples)
+	for i := 0; i < samples; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		resp, err := a.client.JszPing(jszOpts)
+		if err != nil {
+			return nil, err
+		}
+		jsz = append(jsz, resp)
+	}
+
+	return streamHealth(opts, jsz), nil
+}
+
+func streamHealth(opts StreamHealthOptions, samples [][]JSZResp) *StreamHealthReport {
+	report := &StreamHealthReport{}
+	if len(samples) == 0 {
+		return report
+	}
+
+	deduped := make([]map[streamKey]*StreamDetail, 0, len(samples))
+	for _, sample := range samples {
+		deduped = append(deduped, dedupStreams(sample))
+	}
+	latest := deduped[len(deduped)-1]
+
+	keys := make([]streamKey, 0, len(latest))
+	for key := range latest {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].account != keys[j].account {
+			return keys[i].account < keys[j].account
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	for _, key := range keys {
+		stream := latest[key]
+		health := StreamHealth{
+			Account: key.account,
+			Name:    key.name,
+		}
+		if stream.Config != nil {
+			health.Replicas = stream.Config.Replicas
+		}
+
+		if stream.Cluster != nil {
+			health.Leader = stream.Cluster.Leader
+			health.Peers = len(stream.Cluster.Replicas)
+			if stream.Cluster.Leader == "" {
+				health.addFinding(HealthFinding{
+					Kind:    FindingNoStreamLeader,
+					Status:  StatusError,
+					Message: "stream has no leader",
+				})
+			} else {
+				health.Peers++
+			}
+			for _, peer := range stream.Cluster.Replicas {
+				if finding := replicaFinding(peer, opts.MaxReplicaLag); finding != nil {
+					health.addFinding(*finding)
+				}
+			}
+			if health.Peers < health.Replicas {
+				health.addFinding(HealthFinding{
+					Kind:    FindingStreamUnderReplicated,
+					Status:  StatusUnavailable,
+					Message: fmt.Sprintf("stream has %d of %d configured replicas", health.Peers, health.Replicas),
+				})
+			}
+		}
+
+		for _, consumer := range growingConsumers(key, deduped) {
+			health.addFinding(HealthFinding{
+				Kind:     FindingConsumerPendingGrowing,
+				Status:   StatusUnavailable,
+				Consumer: consumer,
+				Message:  fmt.Sprintf("consumer pending count grew in each of %d samples", len(deduped)),
+			})
+		}
+
+		if health.Status > report.Status {
+			report.Status = health.Status
+		}
+		report.Streams = append(report.Streams, health)
+	}
+	return report
+}
+
+func (h *StreamHealth) addFinding(finding HealthFinding) {
+	h.Findings = append(h.Findings, finding)
+	if finding.Status > h.Status {
+		h.Status = finding.Status
+	}
+}
+
+func replicaFinding(peer *nats.PeerInfo, maxLag uint64) *HealthFinding {
+	if peer == nil {
+		return nil
+	}
+	switch {
+	case peer.Offline:
+		return &HealthFinding{
+			Kind:    FindingReplicaOffline,
+			Status:  StatusUnavailable,
+			Server:  peer.Name,
+			Message: "stream replica is offline",
+		}
+	case !peer.Current || (maxLag > 0 && peer.Lag > maxLag):
+		return &HealthFinding{
+			Kind:    FindingReplicaLagging,
+			Status:  StatusUnavailable,
+			Server:  peer.Name,
+			Message: fmt.Sprintf("stream replica is lagging (current: %t, lag: %d)", peer.Current, peer.Lag),
+		}
+	}
+	return nil
+}
+
+// dedupStreams collects streams from all servers, keyed by account and stream name.
+// If a stream is reported by multiple servers, stream leader's response is used.
+func dedupStreams(jsz []JSZResp) map[streamKey]*StreamDetail {
+	streams := make(map[streamKey]*StreamDetail)
+	for _, resp := range jsz {
+		for _, acc := range resp.JSInfo.AccountDetails {
+			if acc == nil {
+				continue
+			}
+			for i := range acc.Streams {
+				stream := &acc.Streams[i]
+				key := streamKey{account: acc.Name, name: stream.Name}
+				if _, ok := streams[key]; ok && (stream.Cluster == nil || stream.Cluster.Leader != resp.Server.Name) {
+					continue
+				}
+				streams[key] = stream
+			}
+		}
+	}
+	return streams
+}
+
+// growingConsumers returns names of stream consumers for which the number of pending messages
+// increased between every two consecutive samples.
+func growingConsumers(key streamKey, samples []map[streamKey]*StreamDetail) []string {
+	if len(samples) < 2 {
+		return nil
+	}
+	pending := make(map[string][]uint64)
+	for _, sample := range samples {
+		stream, ok := sample[key]
+		if !ok {
+			return nil
+		}
+		for _, consumer := range stream.Consumer {
+			if consumer != nil {
+				pending[consumer.Name] = append(pending[consumer.Name], consumer.NumPending)
+			}
+		}
+	}
+
+	growing := make([]string, 0)
+	for name, values := range pending {
+		if len(values) != len(samples) {
+			continue
+		}
+		grows := true
+		for i := 1; i < len(values); i++ {
+			if values[i] <= values[i-1] {
+				grows = false
+				break
+			}
+		}
+		if grows {
+			growing = append(growing, name)
+		}
+	}
+	sort.Strings(growing)
+	return growing
+}
@@ -0,0 +1,199 @@
+package sys
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestStreamHealth(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	nc, err := nats.Connect(strings.Join(urls, ","))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer nc.Close()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Error getting JetStream context: %v", err)
+	}
+	_, err = js.AddStream(&nats.StreamConfig{Name: "s1", Subjects: []string{"foo"}, Replicas: 3})
+	if err != nil {
+		t.Fatalf("Error creating stream: %v", err)
+	}
+	_, err = js.AddConsumer("s1", &nats.ConsumerConfig{Durable: "c1", AckPolicy: nats.AckExplicitPolicy})
+	if err != nil {
+		t.Fatalf("Error creating consumer: %v", err)
+	}
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	// replicas may need a moment to become current
+	var report *StreamHealthReport
+	timeout := time.Now().Add(5 * time.Second)
+	for time.Now().Before(timeout) {
+		report, err = sys.StreamHealth(StreamHealthOptions{})
+		if err != nil {
+			t.Fatalf("Unable to fetch stream health: %s", err)
+		}
+		if report.Status == StatusOK {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if report.Status != StatusOK {
+		t.Fatalf("Invalid stream health status; want: %s; got: %+v", StatusOK, report)
+	}
+	if len(report.Streams) != 1 {
+		t.Fatalf("Invalid number of streams: %d; want: %d", len(report.Streams), 1)
+	}
+	stream := report.Streams[0]
+	if stream.Name != "s1" || stream.Account != "JS" || stream.Replicas != 3 || stream.Peers != 3 || stream.Leader == "" {
+		t.Fatalf("Invalid stream health: %+v", stream)
+	}
+}
+
+func TestStreamHealthFindings(t *testing.T) {
+	jszResp := func(server, leader string, pending uint64, replicas ...*nats.PeerInfo) JSZResp {
+		return JSZResp{
+			Server: ServerInfo{Name: server},
+			JSInfo: JSInfo{
+				AccountDetails: []*AccountDetail{
+					{
+						Name: "JS",
+						Streams: []StreamDetail{
+							{
+								Name:     "s1",
+								Config:   &nats.StreamConfig{Name: "s1", Replicas: 3},
+								Cluster:  &nats.ClusterInfo{Leader: leader, Replicas: replicas},
+								Consumer: []*nats.ConsumerInfo{{Name: "c1", NumPending: pending}, {Name: "c2", NumPending: 5}},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	samples := [][]JSZResp{
+		{
+			jszResp("s2", "s1", 0),
+			jszResp("s1", "s1", 1, &nats.PeerInfo{Name: "s2", Current: false, Lag: 3}),
+		},
+		{
+			jszResp("s1", "s1", 2, &nats.PeerInfo{Name: "s2", Current: false, Lag: 3}),
+			jszResp("s2", "s1", 0),
+		},
+		{
+			jszResp("s1", "s1", 10, &nats.PeerInfo{Name: "s2", Current: true, Offline: true}),
+		},
+	}
+
+	report := streamHealth(StreamHealthOptions{}, samples)
+	if report.Status != StatusUnavailable {
+		t.Fatalf("Invalid stream health status; want: %s; got: %s", StatusUnavailable, report.Status)
+	}
+	if len(report.Streams) != 1 {
+		t.Fatalf("Invalid number of streams: %d; want: %d", len(report.Streams), 1)
+	}
+	stream := report.Streams[0]
+	if stream.Peers != 2 || stream.Replicas != 3 {
+		t.Fatalf("Invalid stream peers: %+v", stream)
+	}
+	expected := []HealthFindingKind{FindingReplicaOffline, FindingStreamUnderReplicated, FindingConsumerPendingGrowing}
+	if len(stream.Findings) != len(expected) {
+		t.Fatalf("Invalid number of findings; want: %d; got: %+v", len(expected), stream.Findings)
+	}
+	for i, kind := range expected {
+		if stream.Findings[i].Kind != kind {
+			t.Fatalf("Invalid finding; want: %s; got: %+v", kind, stream.Findings[i])
+		}
+	}
+	if stream.Findings[2].Consumer != "c1" {
+		t.Fatalf("Invalid consumer reported: %+v", stream.Findings[2])
+	}
+
+	// current replicas are only reported as lagging if they exceed configured lag
+	current := [][]JSZResp{{jszResp("s1", "s1", 0, &nats.PeerInfo{Name: "s2", Current: true, Lag: 2}, &nats.PeerInfo{Name: "s3", Current: true})}}
+	if report = streamHealth(StreamHealthOptions{}, current); report.Status != StatusOK {
+		t.Fatalf("Expected current replica not to be reported: %+v", report)
+	}
+	report = streamHealth(StreamHealthOptions{MaxReplicaLag: 1}, current)
+	if report.Status != StatusUnavailable || report.Streams[0].Findings[0].Kind != FindingReplicaLagging {
+		t.Fatalf("Expected replica exceeding max lag to be reported: %+v", report)
+	}
+
+	report = streamHealth(StreamHealthOptions{}, [][]JSZResp{{jszResp("s1", "", 0)}})
+	if report.Status != StatusError || report.Streams[0].Findings[0].Kind != FindingNoStreamLeader {
+		t.Fatalf("Expected stream without leader to be reported: %+v", report)
+	}
+}
+
+// pendingClient serves JSZ responses with a consumer whose pending count grows with each request.
+// Other endpoints are not implemented.
+type pendingClient struct {
+	SysClient
+	calls int
+}
+
+func (c *pendingClient) JszPing(_ JszEventOptions) ([]JSZResp, error) {
+	c.calls++
+	return []JSZResp{{
+		Server: ServerInfo{Name: "s1"},
+		JSInfo: JSInfo{AccountDetails: []*AccountDetail{{
+			Name: "JS",
+			Streams: []StreamDetail{{
+				Name:     "s1",
+				Config:   &nats.StreamConfig{Name: "s1", Replicas: 1},
+				Consumer: []*nats.ConsumerInfo{{Name: "c1", NumPending: uint64(c.calls * 10)}},
+			}},
+		}}},
+	}}, nil
+}
+
+func TestStreamHealthDefaultSamples(t *testing.T) {
+	client := &pendingClient{}
+	report, err := NewAnalyzer(client).StreamHealth(StreamHealthOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch stream health: %s", err)
+	}
+	if client.calls != DefaultStreamHealthSamples {
+		t.Fatalf("Invalid number of samples; want: %d; got: %d", DefaultStreamHealthSamples, client.calls)
+	}
+	if len(report.Streams) != 1 || len(report.Streams[0].Findings) != 1 {
+		t.Fatalf("Invalid stream health: %+v", report)
+	}
+	if finding := report.Streams[0].Findings[0]; finding.Kind != FindingConsumerPendingGrowing || finding.Consumer != "c1" {
+		t.Fatalf("Invalid finding: %+v", finding)
+	}
+
+	client = &pendingClient{}
+	if report, err = NewAnalyzer(client).StreamHealth(StreamHealthOptions{Samples: 1}); err != nil {
+		t.Fatalf("Unable to fetch stream health: %s", err)
+	}
+	if client.calls != 1 || report.Status != StatusOK {
+		t.Fatalf("Expected a single sample without findings: %+v", report)
+	}
+}
@@ -0,0 +1,233 @@
+package sys
+
+import (
+	"sort"
+	"strings"
+)
+
+type (
+	// SubjectTree aggregates subscriptions from all servers by subject tokens.
+	// Wildcard subscriptions are stored under "*" and ">" tokens,
+	// so e.g. "foo.*" subscriptions from different servers are merged into a single node.
+	SubjectTree struct {
+		Root *SubjectNode `json:"root"`
+	}
+
+	// SubjectNode is a single subject token in the tree.
+	// Stats contains subscriptions on the node's subject, Total includes subscriptions on all descendant nodes.
+	SubjectNode struct {
+		Token    string                  `json:"token,omitempty"`
+		Subject  string                  `json:"subject,omitempty"`
+		Stats    SubjectStats            `json:"stats"`
+		Total    SubjectStats            `json:"total"`
+		Children map[string]*SubjectNode `json:"children,omitempty"`
+
+		accounts      map[string]struct{}
+		queues        map[string]struct{}
+		totalAccounts map[string]struct{}
+		totalQueues   map[string]struct{}
+	}
+
+	// SubjectStats contains aggregated subscription counts.
+	SubjectStats struct {
+		Subscribers int   `json:"subscribers"`
+		QueueGroups int   `json:"queue_groups"`
+		Accounts    int   `json:"accounts"`
+		Msgs        int64 `json:"msgs"`
+	}
+)
+
+// SubjectTree builds a subject tree from client subscriptions on all servers.
+// If account is empty, subscriptions from all accounts are included.
+// Interest propagated between servers is skipped, so each subscription is counted once.
+func (a *Analyzer) SubjectTree(account string) (*SubjectTree, error) {
+	subsz, err := subszAll(a.client, SubszOptions{
+		Account:       account,
+		Subscriptions: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	connz, err := connzAll(a.client, ConnzEventOptions{
+		ConnzOptions: ConnzOptions{
+			Username: true,
+			Account:  account,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make(map[string]map[uint64]struct{}, len(connz))
+	for _, resp := range connz {
+		cids := make(map[uint64]struct{}, len(resp.Connz.Conns))
+		for _, conn := range resp.Connz.Conns {
+			if conn != nil {
+				cids[conn.Cid] = struct{}{}
+			}
+		}
+		clients[resp.Server.ID] = cids
+	}
+
+	tree := NewSubjectTree()
+	for _, resp := range subsz {
+		for _, sub := range resp.Subsz.Subs {
+			if _, ok := clients[resp.Server.ID][sub.Cid]; ok {
+				tree.Insert(sub)
+			}
+		}
+	}
+	return tree, nil
+}
+
+func NewSubjectTree() *SubjectTree {
+	return &SubjectTree{Root: newSubjectNode("", "")}
+}
+
+func newSubjectNode(token, subject string) *SubjectNode {
+	return &SubjectNode{
+		Token:         token,
+		Subject:       subject,
+		Children:      make(map[string]*SubjectNode),
+		accounts:      make(map[string]struct{}),
+		queues:        make(map[string]struct{}),
+		totalAccounts: make(map[string]struct{}),
+		totalQueues:   make(map[string]struct{}),
+	}
+}
+
+// Insert adds a subscription to the tree.
+func (t *SubjectTree) Insert(sub SubDetail) {
+	if sub.Subject == "" {
+		return
+	}
+	var queue string
+	if sub.Queue != "" {
+		queue = sub.Account + " " + sub.Subject + " " + sub.Queue
+	}
+
+	node := t.Root
+	node.Total.add(sub, queue, node.totalAccounts, node.totalQueues)
+	for _, token := range strings.Split(sub.Subject, ".") {
+		child, ok := node.Children[token]
+		if !ok {
+			subject := token
+			if node.Subject != "" {
+				subject = node.Subject + "." + token
+			}
+			child = newSubjectNode(token, subject)
+			node.Children[token] = child
+		}
+		node = child
+		node.Total.add(sub, queue, node.totalAccounts, node.totalQueues)
+	}
+	node.Stats.add(sub, queue, node.accounts, node.queues)
+}
+
+func (s *SubjectStats) add(sub SubDetail, queue string, accounts, queues map[string]struct{}) {
+	s.Subscribers++
+	s.Msgs += sub.Msgs
+	accounts[sub.Account] = struct{}{}
+	s.Accounts = len(accounts)
+	if queue != "" {
+		queues[queue] = struct{}{}
+		s.QueueGroups = len(queues)
+	}
+}
+
+// Find returns the node for given subject prefix, e.g. "orders.eu" or "orders.*".
+// Tokens are matched literally, so wildcard subscriptions covering the prefix (e.g. "orders.*" for "orders.eu")
+// are not included, see Matching. Nil is returned if there are no subscriptions with given prefix.
+func (t *SubjectTree) Find(prefix string) *SubjectNode {
+	if prefix == "" {
+		return t.Root
+	}
+	node := t.Root
+	for _, token := range strings.Split(prefix, ".") {
+		child, ok := node.Children[token]
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
+
+// Matching returns nodes with subscriptions receiving messages published on given literal subject,
+// including wildcard subscriptions, e.g. "orders.eu", "orders.*", "*.eu" and ">" for "orders.eu".
+// Nodes are sorted by subject. Nil is returned if the subject is not a literal subject.
+func (t *SubjectTree) Matching(subject string) []*SubjectNode {
+	if !isLiteralSubject(subject) {
+		return nil
+	}
+	nodes := make([]*SubjectNode, 0)
+	t.Root.matching(strings.Split(subject, "."), &nodes)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Subject < nodes[j].Subject
+	})
+	return nodes
+}
+
+func (n *SubjectNode) matching(tokens []string, nodes *[]*SubjectNode) {
+	if len(tokens) == 0 {
+		if n.Stats.Subscribers > 0 {
+			*nodes = append(*nodes, n)
+		}
+		return
+	}
+	if full, ok := n.Children[">"]; ok && full.Stats.Subscribers > 0 {
+		*nodes = append(*nodes, full)
+	}
+	for _, token := range []string{tokens[0], "*"} {
+		if child, ok := n.Children[token]; ok {
+			child.matching(tokens[1:], nodes)
+		}
+	}
+}
+
+// Walk calls fn for each node in the tree, in depth-first order with children sorted by token.
+// If fn returns false, descendants of the node are skipped.
+func (t *SubjectTree) Walk(fn func(*SubjectNode) bool) {
+	t.Root.walk(fn)
+}
+
+func (n *SubjectNode) walk(fn func(*SubjectNode) bool) {
+	if !fn(n) {
+		return
+	}
+	tokens := make([]string, 0, len(n.Children))
+	for token := range n.Children {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	for _, token := range tokens {
+		n.Children[token].walk(fn)
+	}
+}
+
+// Hot returns up to limit subjects with the highest number of messages delivered to their subscribers.
+func (t *SubjectTree) Hot(limit int) []*SubjectNode {
+	return t.top(limit, func(n *SubjectNode) bool { return n.Stats.Subscribers > 0 })
+}
+
+// FullWildcards returns subjects ending with ">" wildcard, sorted by the number of messages delivered.
+func (t *SubjectTree) FullWildcards() []*SubjectNode {
+	return t.top(-1, func(n *SubjectNode) bool { return n.Token == ">" && n.Stats.Subscribers > 0 })
+}
+
+func (t *SubjectTree) top(limit int, filter func(*SubjectNode) bool) []*SubjectNode {
+	nodes := make([]*SubjectNode, 0)
+	t.Walk(func(n *SubjectNode) bool {
+		if filter(n) {
+			nodes = append(nodes, n)
+		}
+		return true
+	})
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Stats.Msgs > nodes[j].Stats.Msgs
+	})
+	if limit >= 0 && len(nodes) > limit {
+		nodes = nodes[:limit]
+	}
+	return nodes
+}
@@ -0,0 +1,132 @@
+package sys
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestSubjectTree(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	if len(c.servers) != 3 {
+		t.Fatalf("Unexpected number of servers started: %d; want: %d", len(c.servers), 3)
+	}
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+
+	for i, s := range c.servers {
+		nc, err := nats.Connect(s.ClientURL())
+		if err != nil {
+			t.Fatalf("Error establishing connection: %s", err)
+		}
+		defer nc.Close()
+		if _, err := nc.QueueSubscribeSync("orders.*", "workers"); err != nil {
+			t.Fatalf("Error subscribing: %s", err)
+		}
+		if i == 0 {
+			if _, err := nc.SubscribeSync("orders.>"); err != nil {
+				t.Fatalf("Error subscribing: %s", err)
+			}
+		}
+		if err := nc.Flush(); err != nil {
+			t.Fatalf("Error flushing connection: %s", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	tree, err := sys.SubjectTree("JS")
+	if err != nil {
+		t.Fatalf("Unable to build subject tree: %s", err)
+	}
+
+	orders := tree.Find("orders")
+	if orders == nil {
+		t.Fatalf("Expected %q node in the tree", "orders")
+	}
+	if orders.Total.Subscribers != 4 || orders.Stats.Subscribers != 0 {
+		t.Fatalf("Invalid %q stats: %+v %+v", "orders", orders.Stats, orders.Total)
+	}
+	wildcard := tree.Find("orders.*")
+	if wildcard == nil {
+		t.Fatalf("Expected %q node in the tree", "orders.*")
+	}
+	if wildcard.Stats.Subscribers != 3 || wildcard.Stats.QueueGroups != 1 || wildcard.Stats.Accounts != 1 {
+		t.Fatalf("Invalid %q stats: %+v", "orders.*", wildcard.Stats)
+	}
+	full := tree.FullWildcards()
+	if len(full) != 1 || full[0].Subject != "orders.>" {
+		t.Fatalf("Invalid full wildcard subscriptions: %+v", full)
+	}
+}
+
+func TestSubjectTreeQueries(t *testing.T) {
+	tree := NewSubjectTree()
+	for _, sub := range []SubDetail{
+		{Account: "A", Subject: "foo.bar", Msgs: 10},
+		{Account: "B", Subject: "foo.bar", Msgs: 5},
+		{Account: "A", Subject: "foo.baz", Queue: "q", Msgs: 100},
+		{Account: "A", Subject: "foo.baz", Queue: "q", Msgs: 50},
+		{Account: "A", Subject: ">", Msgs: 1},
+	} {
+		tree.Insert(sub)
+	}
+
+	if tree.Root.Total.Subscribers != 5 || tree.Root.Total.Accounts != 2 || tree.Root.Total.QueueGroups != 1 || tree.Root.Total.Msgs != 166 {
+		t.Fatalf("Invalid root stats: %+v", tree.Root.Total)
+	}
+	bar := tree.Find("foo.bar")
+	if bar == nil || bar.Stats.Subscribers != 2 || bar.Stats.Accounts != 2 || bar.Stats.Msgs != 15 {
+		t.Fatalf("Invalid %q node: %+v", "foo.bar", bar)
+	}
+	if tree.Find("foo.qux") != nil {
+		t.Fatalf("Expected no node for %q", "foo.qux")
+	}
+
+	hot := tree.Hot(2)
+	if len(hot) != 2 || hot[0].Subject != "foo.baz" || hot[1].Subject != "foo.bar" {
+		t.Fatalf("Invalid hot subjects: %+v", hot)
+	}
+
+	var subjects []string
+	tree.Walk(func(n *SubjectNode) bool {
+		subjects = append(subjects, n.Subject)
+		return n.Token != "foo"
+	})
+	if strings.Join(subjects, ",") != ",>,foo" {
+		t.Fatalf("Invalid walk order: %v", subjects)
+	}
+
+	tree.Insert(SubDetail{Account: "A", Subject: "foo.*"})
+	tree.Insert(SubDetail{Account: "A", Subject: "*.bar.>"})
+	for subject, expected := range map[string]string{
+		"foo.bar":     ">,foo.*,foo.bar",
+		"foo.qux":     ">,foo.*",
+		"foo.bar.baz": "*.bar.>,>",
+		"foo.*":       "",
+	} {
+		var matching []string
+		for _, node := range tree.Matching(subject) {
+			matching = append(matching, node.Subject)
+		}
+		if strings.Join(matching, ",") != expected {
+			t.Fatalf("Invalid nodes matching %q; want: %s; got: %v", subject, expected, matching)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package systest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/piotrpio/nats-sys-client/pkg/sys"
+)
+
+// Credentials of the system account user, available on all servers started by NewCluster.
+const (
+	SystemUser     = "admin"
+	SystemPassword = "s3cr3t!"
+)
+
+const (
+	systemAccount  = "$SYS"
+	clusterTimeout = 10 * time.Second
+)
+
+var ErrClusterNotReady = errors.New("cluster not ready")
+
+type (
+	// Cluster is a set of embedded NATS servers, optionally forming a super-cluster with gateways
+	// and with leafnode servers connected to the first cluster.
+	Cluster struct {
+		servers   []*server.Server
+		leafNodes []*server.Server
+		sysConn   *nats.Conn
+		sys       *sys.System
+		opts      *clusterOpts
+	}
+
+	// Account is a NATS account created on all servers.
+	Account struct {
+		Name      string
+		JetStream bool
+		Users     []User
+	}
+
+	// User is an account user authenticating with username and password.
+	User struct {
+		Name     string
+		Password string
+	}
+
+	ClusterOpt func(*clusterOpts) error
+
+	clusterOpts struct {
+		servers   int
+		jetStream bool
+		clusters  []string
+		leafNodes int
+		accounts  []Account
+		sysOpts   []sys.SysClientOpt
+	}
+
+	leafNodeRemote struct {
+		account string
+		user    User
+	}
+
+	serverPorts struct {
+		cluster  int
+		gateway  int
+		leafNode int
+	}
+)
+
+// WithServers sets the number of servers in each cluster, 3 by default.
+func WithServers(count int) ClusterOpt {
+	return func(opts *clusterOpts) error {
+		if count <= 0 {
+			return fmt.Errorf("%w: server count has to be greater than 0", sys.ErrValidation)
+		}
+		opts.servers = count
+		return nil
+	}
+}
+
+// WithJetStream enables JetStream on cluster servers (leafnode servers do not run JetStream).
+func WithJetStream() ClusterOpt {
+	return func(opts *clusterOpts) error {
+		opts.jetStream = true
+		return nil
+	}
+}
+
+// WithClusters sets cluster names. If more than one name is given, clusters are connected with gateways.
+func WithClusters(names ...string) ClusterOpt {
+	return func(opts *clusterOpts) error {
+		if len(names) == 0 {
+			return fmt.Errorf("%w: at least one cluster name is required", sys.ErrValidation)
+		}
+		seen := make(map[string]struct{}, len(names))
+		for _, name := range names {
+			if name == "" {
+				return fmt.Errorf("%w: cluster name cannot be empty", sys.ErrValidation)
+			}
+			if _, ok := seen[name]; ok {
+				return fmt.Errorf("%w: duplicate cluster name %q", sys.ErrValidation, name)
+			}
+			seen[name] = struct{}{}
+		}
+		opts.clusters = names
+		return nil
+	}
+}
+
+// WithLeafNodes sets the number of standalone leafnode servers connected to the first cluster.
+func WithLeafNodes(count int) ClusterOpt {
+	return func(opts *clusterOpts) error {
+		if count < 0 {
+			return fmt.Errorf("%w: leafnode count cannot be negative", sys.ErrValidation)
+		}
+		opts.leafNodes = count
+		return nil
+	}
+}
+
+// WithAccounts replaces the default "JS" account (with user "pp" and password "foo").
+// The first user of the first account is used for connections without credentials.
+func WithAccounts(accounts ...Account) ClusterOpt {
+	return func(opts *clusterOpts) error {
+		for _, acc := range accounts {
+			if acc.Name == "" || acc.Name == systemAccount {
+				return fmt.Errorf("%w: invalid account name %q", sys.ErrValidation, acc.Name)
+			}
+		}
+		opts.accounts = accounts
+		return nil
+	}
+}
+
+// WithSysClientOpts sets options passed to the system client returned by Cluster.System.
+func WithSysClientOpts(sysOpts ...sys.SysClientOpt) ClusterOpt {
+	return func(opts *clusterOpts) error {
+		opts.sysOpts = sysOpts
+		return nil
+	}
+}
+
+// NewCluster starts servers and waits until all of them respond to system requests.
+// Servers listen on random ports and are shut down when the test finishes.
+func NewCluster(t testing.TB, opts ...ClusterOpt) *Cluster {
+	t.Helper()
+	clusterOpts := &clusterOpts{
+		servers:  3,
+		clusters: []string{"C1"},
+	}
+	for _, opt := range opts {
+		if err := opt(clusterOpts); err != nil {
+			t.Fatalf("Invalid cluster options: %s", err)
+		}
+	}
+	if len(clusterOpts.accounts) == 0 {
+		clusterOpts.accounts = []Account{
+			{Name: "JS", JetStream: clusterOpts.jetStream, Users: []User{{Name: "pp", Password: "foo"}}},
+		}
+	}
+
+	c := &Cluster{opts: clusterOpts}
+	t.Cleanup(c.Shutdown)
+
+	ports := make(map[string][]serverPorts, len(clusterOpts.clusters))
+	for _, name := range clusterOpts.clusters {
+		for i := 0; i < clusterOpts.servers; i++ {
+			p, err := freePorts(3)
+			if err != nil {
+				t.Fatalf("Unable to reserve ports: %s", err)
+			}
+			ports[name] = append(ports[name], serverPorts{cluster: p[0], gateway: p[1], leafNode: p[2]})
+		}
+	}
+
+	dir := t.TempDir()
+	for _, name := range clusterOpts.clusters {
+		for i := 0; i < clusterOpts.servers; i++ {
+			conf := c.serverConfig(dir, name, i, ports)
+			c.servers = append(c.servers, startServer(t, dir, fmt.Sprintf("%s-s%d", name, i+1), conf))
+		}
+	}
+	for i := 0; i < clusterOpts.leafNodes; i++ {
+		conf := c.leafNodeConfig(i, ports[clusterOpts.clusters[0]])
+		c.leafNodes = append(c.leafNodes, startServer(t, dir, fmt.Sprintf("L%d", i+1), conf))
+	}
+
+	if err := c.waitReady(); err != nil {
+		t.Fatalf("Error starting cluster: %s", err)
+	}
+	return c
+}
+
+// System returns a system client connected to the cluster.
+// It expects responses from all servers, including leafnodes.
+func (c *Cluster) System() *sys.System {
+	return c.sys
+}
+
+// Servers returns all cluster servers, in order of clusters.
+func (c *Cluster) Servers() []*server.Server {
+	return c.servers
+}
+
+// LeafNodes returns leafnode servers.
+func (c *Cluster) LeafNodes() []*server.Server {
+	return c.leafNodes
+}
+
+// ClientURLs returns client URLs of all cluster servers.
+func (c *Cluster) ClientURLs() []string {
+	urls := make([]string, 0, len(c.servers))
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	return urls
+}
+
+// Connect creates a client connection to the cluster.
+// Without credentials, the connection is bound to the first account.
+func (c *Cluster) Connect(opts ...nats.Option) (*nats.Conn, error) {
+	return nats.Connect(strings.Join(c.ClientURLs(), ","), opts...)
+}
+
+// Shutdown closes the system connection and shuts down all servers.
+func (c *Cluster) Shutdown() {
+	if c.sysConn != nil {
+		c.sysConn.Close()
+	}
+	for _, s := range c.leafNodes {
+		s.Shutdown()
+	}
+	for _, s := range c.servers {
+		s.Shutdown()
+	}
+}
+
+func (c *Cluster) waitReady() error {
+	sysConn, err := c.Connect(nats.UserInfo(SystemUser, SystemPassword))
+	if err != nil {
+		return err
+	}
+	c.sysConn = sysConn
+	total := len(c.servers) + len(c.leafNodes)
+	sysOpts := append([]sys.SysClientOpt{sys.ServerCount(total)}, c.opts.sysOpts...)
+	if c.sys, err = sys.NewSysClient(sysConn, sysOpts...); err != nil {
+		return err
+	}
+
+	timeout := time.Now().Add(clusterTimeout)
+	for time.Now().Before(timeout) {
+		if c.ready() {
+			resp, err := c.sys.RequestMany("$SYS.REQ.SERVER.PING", nil,
+				sys.WithRequestManyMaxWait(time.Second), sys.WithRequestManyCount(total))
+			if err == nil && len(resp) == total {
+				return nil
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("%w: servers did not connect within %s", ErrClusterNotReady, clusterTimeout)
+}
+
+func (c *Cluster) ready() bool {
+	var jsLeader bool
+	for _, s := range c.servers {
+		if s.NumRoutes() < c.opts.servers-1 {
+			return false
+		}
+		if s.NumOutboundGateways() < len(c.opts.clusters)-1 {
+			return false
+		}
+		if c.opts.jetStream {
+			if !s.JetStreamIsCurrent() {
+				return false
+			}
+			jsLeader = jsLeader || s.JetStreamIsLeader()
+		}
+	}
+	if c.opts.jetStream && len(c.servers) > 1 && !jsLeader {
+		return false
+	}
+	for _, s := range c.leafNodes {
+		if s.NumLeafNodes() < len(c.leafNodeRemotes()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Cluster) serverConfig(dir, clusterName string, idx int, ports map[string][]serverPorts) string {
+	own := ports[clusterName][idx]
+	conf := &strings.Builder{}
+	fmt.Fprintf(conf, "server_name: %s\n", strconv.Quote(fmt.Sprintf("%s-s%d", clusterName, idx+1)))
+	conf.WriteString("listen: \"127.0.0.1:-1\"\n")
+	c.writeAccounts(conf)
+
+	if c.opts.jetStream {
+		storeDir := filepath.Join(dir, clusterName, strconv.Itoa(idx+1))
+		fmt.Fprintf(conf, "jetstream {\n  store_dir: %s\n}\n", strconv.Quote(storeDir))
+	}
+
+	if c.opts.servers > 1 {
+		fmt.Fprintf(conf, "cluster {\n  name: %s\n  listen: \"127.0.0.1:%d\"\n  routes: [\n", strconv.Quote(clusterName), own.cluster)
+		for i, peer := range ports[clusterName] {
+			if i != idx {
+				fmt.Fprintf(conf, "    \"nats-route://127.0.0.1:%d\"\n", peer.cluster)
+			}
+		}
+		conf.WriteString("  ]\n}\n")
+	}
+
+	if len(c.opts.clusters) > 1 {
+		fmt.Fprintf(conf, "gateway {\n  name: %s\n  listen: \"127.0.0.1:%d\"\n  gateways: [\n", strconv.Quote(clusterName), own.gateway)
+		for _, name := range c.opts.clusters {
+			urls := make([]string, 0, len(ports[name]))
+			for _, peer := range ports[name] {
+				urls = append(urls, strconv.Quote(fmt.Sprintf("nats://127.0.0.1:%d", peer.gateway)))
+			}
+			fmt.Fprintf(conf, "    { name: %s, urls: [%s] }\n", strconv.Quote(name), strings.Join(urls, ", "))
+		}
+		conf.WriteString("  ]\n}\n")
+	}
+
+	if c.opts.leafNodes > 0 && clusterName == c.opts.clusters[0] {
+		fmt.Fprintf(conf, "leafnodes {\n  listen: \"127.0.0.1:%d\"\n}\n", own.leafNode)
+	}
+	return conf.String()
+}
+
+func (c *Cluster) leafNodeConfig(idx int, hub []serverPorts) string {
+	conf := &strings.Builder{}
+	fmt.Fprintf(conf, "server_name: %s\n", strconv.Quote(fmt.Sprintf("L%d", idx+1)))
+	conf.WriteString("listen: \"127.0.0.1:-1\"\n")
+	c.writeAccounts(conf)
+
+	conf.WriteString("leafnodes {\n  remotes: [\n")
+	for _, remote := range c.leafNodeRemotes() {
+		urls := make([]string, 0, len(hub))
+		for _, p := range hub {
+			u := url.URL{
+				Scheme: "nats-leaf",
+				User:   url.UserPassword(remote.user.Name, remote.user.Password),
+				Host:   fmt.Sprintf("127.0.0.1:%d", p.leafNode),
+			}
+			urls = append(urls, strconv.Quote(u.String()))
+		}
+		fmt.Fprintf(conf, "    { urls: [%s], account: %s }\n", strings.Join(urls, ", "), strconv.Quote(remote.account))
+	}
+	conf.WriteString("  ]\n}\n")
+	return conf.String()
+}
+
+// leafNodeRemotes returns accounts bound by leafnode servers to the first cluster,
+// along with the user used to authenticate the leafnode connection.
+func (c *Cluster) leafNodeRemotes() []leafNodeRemote {
+	remotes := []leafNodeRemote{
+		{account: systemAccount, user: User{Name: SystemUser, Password: SystemPassword}},
+	}
+	for _, acc := range c.opts.accounts {
+		if len(acc.Users) > 0 {
+			remotes = append(remotes, leafNodeRemote{account: acc.Name, user: acc.Users[0]})
+		}
+	}
+	return remotes
+}
+
+func (c *Cluster) writeAccounts(conf *strings.Builder) {
+	conf.WriteString("accounts {\n")
+	fmt.Fprintf(conf, "  %s { users: [ { user: %s, password: %s } ] }\n",
+		strconv.Quote(systemAccount), strconv.Quote(SystemUser), strconv.Quote(SystemPassword))
+	for _, acc := range c.opts.accounts {
+		fmt.Fprintf(conf, "  %s {\n", strconv.Quote(acc.Name))
+		if acc.JetStream && c.opts.jetStream {
+			conf.WriteString("    jetstream: enabled\n")
+		}
+		conf.WriteString("    users: [\n")
+		for _, user := range acc.Users {
+			fmt.Fprintf(conf, "      { user: %s, password: %s }\n", strconv.Quote(user.Name), strconv.Quote(user.Password))
+		}
+		conf.WriteString("    ]\n  }\n")
+	}
+	conf.WriteString("}\n")
+	fmt.Fprintf(conf, "system_account: %s\n", strconv.Quote(systemAccount))
+	if len(c.opts.accounts[0].Users) > 0 {
+		fmt.Fprintf(conf, "no_auth_user: %s\n", strconv.Quote(c.opts.accounts[0].Users[0].Name))
+	}
+}
+
+func startServer(t testing.TB, dir, name, conf string) *server.Server {
+	t.Helper()
+	confFile := filepath.Join(dir, name+".conf")
+	if err := os.WriteFile(confFile, []byte(conf), 0600); err != nil {
+		t.Fatalf("Error writing config file: %s", err)
+	}
+	opts, err := server.ProcessConfigFile(confFile)
+	if err != nil {
+		t.Fatalf("Error processing config file: %v", err)
+	}
+	opts.NoLog = true
+
+	s, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatalf("Error creating server: %s", err)
+	}
+	s.Start()
+
+	if !s.ReadyForConnections(clusterTimeout) {
+		t.Fatalf("Unable to start NATS Server %q", name)
+	}
+	return s
+}
+
+// freePorts returns ports which are not in use at the time of the call.
+func freePorts(count int) ([]int, error) {
+	ports := make([]int, 0, count)
+	listeners := make([]net.Listener, 0, count)
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	for i := 0; i < count; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+		ports = append(ports, l.Addr().(*net.TCPAddr).Port)
+	}
+	return ports, nil
+}
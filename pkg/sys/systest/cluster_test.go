@@ -0,0 +1,94 @@
+package systest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/piotrpio/nats-sys-client/pkg/sys"
+)
+
+func TestNewCluster(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []ClusterOpt
+		expectedServers int
+		jetStream       bool
+	}{
+		{
+			name:            "default cluster",
+			expectedServers: 3,
+		},
+		{
+			name:            "jetstream cluster",
+			opts:            []ClusterOpt{WithJetStream()},
+			expectedServers: 3,
+			jetStream:       true,
+		},
+		{
+			name: "super-cluster with leafnodes",
+			opts: []ClusterOpt{
+				WithServers(2),
+				WithClusters("C1", "C2"),
+				WithLeafNodes(1),
+				WithAccounts(Account{Name: "A", Users: []User{{Name: "a", Password: "p@ss:word"}}}),
+			},
+			expectedServers: 5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCluster(t, test.opts...)
+			defer c.Shutdown()
+
+			resp, err := c.System().VarzPing(sys.VarzEventOptions{})
+			if err != nil {
+				t.Fatalf("Unable to fetch VARZ: %s", err)
+			}
+			if len(resp) != test.expectedServers {
+				t.Fatalf("Invalid number of responses: %d; want: %d", len(resp), test.expectedServers)
+			}
+			if len(c.Servers())+len(c.LeafNodes()) != test.expectedServers {
+				t.Fatalf("Invalid number of servers: %d; want: %d", len(c.Servers())+len(c.LeafNodes()), test.expectedServers)
+			}
+
+			nc, err := c.Connect()
+			if err != nil {
+				t.Fatalf("Error establishing connection: %s", err)
+			}
+			defer nc.Close()
+			if !test.jetStream {
+				return
+			}
+			js, err := nc.JetStream()
+			if err != nil {
+				t.Fatalf("Error getting JetStream context: %v", err)
+			}
+			if _, err := js.AddStream(&nats.StreamConfig{Name: "s1", Subjects: []string{"foo"}, Replicas: 3}); err != nil {
+				t.Fatalf("Error creating stream: %v", err)
+			}
+		})
+	}
+}
+
+func TestClusterOpts(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  ClusterOpt
+	}{
+		{name: "invalid server count", opt: WithServers(0)},
+		{name: "no cluster names", opt: WithClusters()},
+		{name: "duplicate cluster names", opt: WithClusters("C1", "C1")},
+		{name: "negative leafnode count", opt: WithLeafNodes(-1)},
+		{name: "system account", opt: WithAccounts(Account{Name: "$SYS"})},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.opt(&clusterOpts{}); !errors.Is(err, sys.ErrValidation) {
+				t.Fatalf("Expected error; want: %s; got: %s", sys.ErrValidation, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+// Package systest provides utilities for testing code which uses the NATS system API client.
+package systest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/piotrpio/nats-sys-client/pkg/sys"
+)
+
+// Endpoint identifies a monitoring endpoint served by Fake.
+type Endpoint = sys.Endpoint
+
+// Possible endpoints
+const (
+	EndpointVarz    = sys.EndpointVarz
+	EndpointConnz   = sys.EndpointConnz
+	EndpointSubsz   = sys.EndpointSubsz
+	EndpointJsz     = sys.EndpointJsz
+	EndpointStatsz  = sys.EndpointStatsz
+	EndpointHealthz = sys.EndpointHealthz
+)
+
+// AllServers can be passed instead of server ID to SetError and SetLatency to apply the setting to all servers.
+const AllServers = ""
+
+// Fake is an in-memory implementation of sys.SysClient serving programmed responses.
+//
+// Single-server requests return the response set for a given server ID,
+// PING requests return responses from all servers (in the order they were first set),
+// skipping missing servers, servers with an error set and servers which do not respond within the timeout.
+type Fake struct {
+	mu        sync.Mutex
+	responses map[Endpoint]map[string]interface{}
+	order     map[Endpoint][]string
+	errs      map[Endpoint]map[string]error
+	latency   map[string]time.Duration
+	missing   map[string]struct{}
+	timeout   time.Duration
+}
+
+var _ sys.SysClient = (*Fake)(nil)
+
+func NewFake() *Fake {
+	return &Fake{
+		responses: make(map[Endpoint]map[string]interface{}),
+		order:     make(map[Endpoint][]string),
+		errs:      make(map[Endpoint]map[string]error),
+		latency:   make(map[string]time.Duration),
+		missing:   make(map[string]struct{}),
+		timeout:   sys.DefaultRequestTimeout,
+	}
+}
+
+// SetTimeout sets the request timeout. Servers with latency greater than the timeout
+// do not respond and single-server requests fail with nats.ErrTimeout.
+func (f *Fake) SetTimeout(timeout time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.timeout = timeout
+}
+
+// SetLatency sets the time it takes a server to respond.
+// Use AllServers to set default latency.
+func (f *Fake) SetLatency(id string, latency time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency[id] = latency
+}
+
+// SetError makes requests to the server on given endpoint fail with err.
+// If id is AllServers, PING requests fail as well. Setting nil error removes the error.
+func (f *Fake) SetError(endpoint Endpoint, id string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errs[endpoint], id)
+		return
+	}
+	if _, ok := f.errs[endpoint]; !ok {
+		f.errs[endpoint] = make(map[string]error)
+	}
+	f.errs[endpoint][id] = err
+}
+
+// SetMissing simulates a server which does not respond at all, as if it was shut down.
+func (f *Fake) SetMissing(id string, missing bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if missing {
+		f.missing[id] = struct{}{}
+		return
+	}
+	delete(f.missing, id)
+}
+
+func (f *Fake) SetVarz(responses ...sys.VarzResp) {
+	for _, resp := range responses {
+		f.set(EndpointVarz, resp.Server.ID, resp)
+	}
+}
+
+func (f *Fake) SetConnz(responses ...sys.ConnzResp) {
+	for _, resp := range responses {
+		f.set(EndpointConnz, resp.Server.ID, resp)
+	}
+}
+
+func (f *Fake) SetSubsz(responses ...sys.SubszResp) {
+	for _, resp := range responses {
+		f.set(EndpointSubsz, resp.Server.ID, resp)
+	}
+}
+
+func (f *Fake) SetJsz(responses ...sys.JSZResp) {
+	for _, resp := range responses {
+		f.set(EndpointJsz, resp.Server.ID, resp)
+	}
+}
+
+func (f *Fake) SetStatsz(responses ...sys.ServerStatszResp) {
+	for _, resp := range responses {
+		f.set(EndpointStatsz, resp.Server.ID, resp)
+	}
+}
+
+func (f *Fake) SetHealthz(responses ...sys.HealthzResp) {
+	for _, resp := range responses {
+		f.set(EndpointHealthz, resp.Server.ID, resp)
+	}
+}
+
+func (f *Fake) set(endpoint Endpoint, id string, resp interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.responses[endpoint]; !ok {
+		f.responses[endpoint] = make(map[string]interface{})
+	}
+	if _, ok := f.responses[endpoint][id]; !ok {
+		f.order[endpoint] = append(f.order[endpoint], id)
+	}
+	f.responses[endpoint][id] = resp
+}
+
+func (f *Fake) serverLatency(id string) time.Duration {
+	if latency, ok := f.latency[id]; ok {
+		return latency
+	}
+	return f.latency[AllServers]
+}
+
+func (f *Fake) request(endpoint Endpoint, id string) (interface{}, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: server id cannot be empty", sys.ErrValidation)
+	}
+	f.mu.Lock()
+	_, missing := f.missing[id]
+	resp, ok := f.responses[endpoint][id]
+	err, failed := f.errs[endpoint][id]
+	if !failed {
+		err, failed = f.errs[endpoint][AllServers]
+	}
+	latency := f.serverLatency(id)
+	timeout := f.timeout
+	f.mu.Unlock()
+
+	if missing || !ok {
+		return nil, fmt.Errorf("%w: %s", sys.ErrInvalidServerID, id)
+	}
+	if latency > timeout {
+		time.Sleep(timeout)
+		return nil, nats.ErrTimeout
+	}
+	time.Sleep(latency)
+	if failed {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (f *Fake) ping(endpoint Endpoint) ([]interface{}, error) {
+	f.mu.Lock()
+	if err, ok := f.errs[endpoint][AllServers]; ok {
+		f.mu.Unlock()
+		return nil, err
+	}
+	var wait time.Duration
+	res := make([]interface{}, 0, len(f.order[endpoint]))
+	for _, id := range f.order[endpoint] {
+		if _, ok := f.missing[id]; ok {
+			continue
+		}
+		if _, ok := f.errs[endpoint][id]; ok {
+			continue
+		}
+		latency := f.serverLatency(id)
+		if latency > f.timeout {
+			wait = f.timeout
+			continue
+		}
+		if latency > wait {
+			wait = latency
+		}
+		res = append(res, f.responses[endpoint][id])
+	}
+	f.mu.Unlock()
+
+	time.Sleep(wait)
+	return res, nil
+}
+
+func (f *Fake) Varz(id string, _ sys.VarzEventOptions) (*sys.VarzResp, error) {
+	resp, err := f.request(EndpointVarz, id)
+	if err != nil {
+		return nil, err
+	}
+	varzResp := resp.(sys.VarzResp)
+	return &varzResp, nil
+}
+
+func (f *Fake) VarzPing(_ sys.VarzEventOptions) ([]sys.VarzResp, error) {
+	resp, err := f.ping(EndpointVarz)
+	if err != nil {
+		return nil, err
+	}
+	srvVarz := make([]sys.VarzResp, 0, len(resp))
+	for _, r := range resp {
+		srvVarz = append(srvVarz, r.(sys.VarzResp))
+	}
+	return srvVarz, nil
+}
+
+func (f *Fake) Connz(id string, _ sys.ConnzEventOptions) (*sys.ConnzResp, error) {
+	resp, err := f.request(EndpointConnz, id)
+	if err != nil {
+		return nil, err
+	}
+	connzResp := resp.(sys.ConnzResp)
+	return &connzResp, nil
+}
+
+func (f *Fake) ConnzPing(_ sys.ConnzEventOptions) ([]sys.ConnzResp, error) {
+	resp, err := f.ping(EndpointConnz)
+	if err != nil {
+		return nil, err
+	}
+	srvConnz := make([]sys.ConnzResp, 0, len(resp))
+	for _, r := range resp {
+		srvConnz = append(srvConnz, r.(sys.ConnzResp))
+	}
+	return srvConnz, nil
+}
+
+func (f *Fake) ServerSubsz(id string, _ sys.SubszOptions) (*sys.SubszResp, error) {
+	resp, err := f.request(EndpointSubsz, id)
+	if err != nil {
+		return nil, err
+	}
+	subszResp := resp.(sys.SubszResp)
+	return &subszResp, nil
+}
+
+func (f *Fake) ServerSubszPing(_ sys.SubszOptions) ([]sys.SubszResp, error) {
+	resp, err := f.ping(EndpointSubsz)
+	if err != nil {
+		return nil, err
+	}
+	srvSubsz := make([]sys.SubszResp, 0, len(resp))
+	for _, r := range resp {
+		srvSubsz = append(srvSubsz, r.(sys.SubszResp))
+	}
+	return srvSubsz, nil
+}
+
+func (f *Fake) Jsz(id string, _ sys.JszEventOptions) (*sys.JSZResp, error) {
+	resp, err := f.request(EndpointJsz, id)
+	if err != nil {
+		return nil, err
+	}
+	jszResp := resp.(sys.JSZResp)
+	return &jszResp, nil
+}
+
+func (f *Fake) JszPing(_ sys.JszEventOptions) ([]sys.JSZResp, error) {
+	resp, err := f.ping(EndpointJsz)
+	if err != nil {
+		return nil, err
+	}
+	srvJsz := make([]sys.JSZResp, 0, len(resp))
+	for _, r := range resp {
+		srvJsz = append(srvJsz, r.(sys.JSZResp))
+	}
+	return srvJsz, nil
+}
+
+func (f *Fake) ServerStatsz(id string, _ sys.StatszEventOptions) (*sys.ServerStatszResp, error) {
+	resp, err := f.request(EndpointStatsz, id)
+	if err != nil {
+		return nil, err
+	}
+	statszResp := resp.(sys.ServerStatszResp)
+	return &statszResp, nil
+}
+
+func (f *Fake) ServerStatszPing(_ sys.StatszEventOptions) ([]sys.ServerStatszResp, error) {
+	resp, err := f.ping(EndpointStatsz)
+	if err != nil {
+		return nil, err
+	}
+	srvStatsz := make([]sys.ServerStatszResp, 0, len(resp))
+	for _, r := range resp {
+		srvStatsz = append(srvStatsz, r.(sys.ServerStatszResp))
+	}
+	return srvStatsz, nil
+}
+
+func (f *Fake) Healthz(id string, _ sys.HealthzOptions) (*sys.HealthzResp, error) {
+	resp, err := f.request(EndpointHealthz, id)
+	if err != nil {
+		return nil, err
+	}
+	healthzResp := resp.(sys.HealthzResp)
+	return &healthzResp, nil
+}
+
+func (f *Fake) HealthzPing(_ sys.HealthzOptions) ([]sys.HealthzResp, error) {
+	resp, err := f.ping(EndpointHealthz)
+	if err != nil {
+		return nil, err
+	}
+	srvHealthz := make([]sys.HealthzResp, 0, len(resp))
+	for _, r := range resp {
+		srvHealthz = append(srvHealthz, r.(sys.HealthzResp))
+	}
+	return srvHealthz, nil
+}
@@ -0,0 +1,187 @@
+package systest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/piotrpio/nats-sys-client/pkg/sys"
+)
+
+func newTestFake() *Fake {
+	fake := NewFake()
+	for _, id := range []string{"S1", "S2", "S3"} {
+		fake.SetVarz(sys.VarzResp{Server: sys.ServerInfo{ID: id}, Varz: sys.Varz{ID: id}})
+		fake.SetHealthz(sys.HealthzResp{Server: sys.ServerInfo{ID: id}, Healthz: sys.Healthz{Status: sys.StatusOK}})
+	}
+	return fake
+}
+
+func TestFakeRequest(t *testing.T) {
+	errTest := errors.New("test error")
+
+	tests := []struct {
+		name      string
+		setup     func(*Fake)
+		id        string
+		withError error
+	}{
+		{
+			name: "with valid id",
+			id:   "S2",
+		},
+		{
+			name:      "with empty id",
+			id:        "",
+			withError: sys.ErrValidation,
+		},
+		{
+			name:      "with invalid id",
+			id:        "asd",
+			withError: sys.ErrInvalidServerID,
+		},
+		{
+			name:      "with missing server",
+			setup:     func(f *Fake) { f.SetMissing("S2", true) },
+			id:        "S2",
+			withError: sys.ErrInvalidServerID,
+		},
+		{
+			name:      "with server error",
+			setup:     func(f *Fake) { f.SetError(EndpointVarz, "S2", errTest) },
+			id:        "S2",
+			withError: errTest,
+		},
+		{
+			name:      "with error for all servers",
+			setup:     func(f *Fake) { f.SetError(EndpointVarz, AllServers, errTest) },
+			id:        "S2",
+			withError: errTest,
+		},
+		{
+			name: "with timeout",
+			setup: func(f *Fake) {
+				f.SetTimeout(10 * time.Millisecond)
+				f.SetLatency("S2", 20*time.Millisecond)
+			},
+			id:        "S2",
+			withError: nats.ErrTimeout,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newTestFake()
+			if test.setup != nil {
+				test.setup(fake)
+			}
+
+			varz, err := fake.Varz(test.id, sys.VarzEventOptions{})
+			if test.withError != nil {
+				if !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error; want: %s; got: %s", test.withError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unable to fetch VARZ: %s", err)
+			}
+			if varz.Varz.ID != test.id {
+				t.Fatalf("Invalid server varz response: %+v", varz)
+			}
+		})
+	}
+}
+
+func TestFakePing(t *testing.T) {
+	errTest := errors.New("test error")
+
+	tests := []struct {
+		name        string
+		setup       func(*Fake)
+		expectedIDs []string
+		minDuration time.Duration
+		withError   error
+	}{
+		{
+			name:        "all servers",
+			expectedIDs: []string{"S1", "S2", "S3"},
+		},
+		{
+			name:        "missing server",
+			setup:       func(f *Fake) { f.SetMissing("S1", true) },
+			expectedIDs: []string{"S2", "S3"},
+		},
+		{
+			name:        "server error",
+			setup:       func(f *Fake) { f.SetError(EndpointHealthz, "S3", errTest) },
+			expectedIDs: []string{"S1", "S2"},
+		},
+		{
+			name: "slow server",
+			setup: func(f *Fake) {
+				f.SetTimeout(20 * time.Millisecond)
+				f.SetLatency(AllServers, 5*time.Millisecond)
+				f.SetLatency("S2", 50*time.Millisecond)
+			},
+			expectedIDs: []string{"S1", "S3"},
+			minDuration: 20 * time.Millisecond,
+		},
+		{
+			name:      "error for all servers",
+			setup:     func(f *Fake) { f.SetError(EndpointHealthz, AllServers, errTest) },
+			withError: errTest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newTestFake()
+			if test.setup != nil {
+				test.setup(fake)
+			}
+
+			start := time.Now()
+			resp, err := fake.HealthzPing(sys.HealthzOptions{})
+			if test.withError != nil {
+				if !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error; want: %s; got: %s", test.withError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unable to fetch HEALTHZ: %s", err)
+			}
+			if dur := time.Since(start); dur < test.minDuration {
+				t.Fatalf("Request terminated too early: %s", dur)
+			}
+			if len(resp) != len(test.expectedIDs) {
+				t.Fatalf("Invalid number of responses: %d; want: %d", len(resp), len(test.expectedIDs))
+			}
+			for i, id := range test.expectedIDs {
+				if resp[i].Server.ID != id {
+					t.Fatalf("Invalid server in response; want: %s; got: %s", id, resp[i].Server.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestFakeAnalyzer(t *testing.T) {
+	fake := NewFake()
+	for id, version := range map[string]string{"S1": "2.9.15", "S2": "2.9.15", "S3": "2.9.14"} {
+		fake.SetVarz(sys.VarzResp{Server: sys.ServerInfo{ID: id, Name: id}, Varz: sys.Varz{ID: id, Name: id, Version: version}})
+	}
+
+	report, err := sys.NewAnalyzer(fake).DriftReport(sys.DriftOptions{})
+	if err != nil {
+		t.Fatalf("Unable to fetch drift report: %s", err)
+	}
+	if report.Servers != 3 || !report.Drifted {
+		t.Fatalf("Invalid drift report: %+v", report)
+	}
+	if version := report.Settings[0]; version.Setting != "version" || len(version.Outliers) != 1 || version.Outliers[0] != "S3" {
+		t.Fatalf("Invalid version drift: %+v", version)
+	}
+}
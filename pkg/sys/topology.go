@@ -0,0 +1,323 @@
+package sys
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Topology is a graph of servers and connections between them.
+	Topology struct {
+		Nodes []TopologyNode `json:"nodes"`
+		Edges []TopologyEdge `json:"edges,omitempty"`
+	}
+
+	// TopologyNode is a server, a remote gateway cluster or an unresolved leafnode remote.
+	TopologyNode struct {
+		ID        string   `json:"id"`
+		Kind      NodeKind `json:"kind"`
+		Name      string   `json:"name"`
+		Cluster   string   `json:"cluster,omitempty"`
+		Domain    string   `json:"domain,omitempty"`
+		Tags      []string `json:"tags,omitempty"`
+		JetStream bool     `json:"jetstream,omitempty"`
+	}
+
+	// TopologyEdge is a connection from a server to another node, as reported by the source server.
+	// Missing edges are configured (or expected in a full mesh of routes), but not established.
+	TopologyEdge struct {
+		Kind     EdgeKind  `json:"kind"`
+		From     string    `json:"from"`
+		To       string    `json:"to"`
+		Account  string    `json:"account,omitempty"`
+		Sent     DataStats `json:"sent"`
+		Received DataStats `json:"received"`
+		Pending  int       `json:"pending,omitempty"`
+		Missing  bool      `json:"missing,omitempty"`
+	}
+
+	NodeKind string
+
+	EdgeKind string
+)
+
+const (
+	NodeServer  NodeKind = "server"  // Server which responded to the system requests
+	NodeCluster NodeKind = "cluster" // Remote cluster connected with a gateway
+	NodeRemote  NodeKind = "remote"  // Leafnode remote URL not matching any of the servers
+)
+
+const (
+	EdgeRoute    EdgeKind = "route"
+	EdgeGateway  EdgeKind = "gateway"
+	EdgeLeafNode EdgeKind = "leafnode"
+)
+
+// Topology builds a graph of all servers, using VARZ for configured routes, gateways and leafnode remotes
+// and STATSZ for established routes and gateways along with their traffic counters.
+// Servers in the same cluster are expected to form a full mesh of routes; missing routes are reported as missing edges.
+// Servers do not report traffic for leafnode connections, so leafnode edges are built from configured remotes only.
+func (a *Analyzer) Topology() (*Topology, error) {
+	varz, err := a.client.VarzPing(VarzEventOptions{})
+	if err != nil {
+		return nil, err
+	}
+	statsz, err := a.client.ServerStatszPing(StatszEventOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return topology(varz, statsz), nil
+}
+
+func topology(varz []VarzResp, statsz []ServerStatszResp) *Topology {
+	top := &Topology{}
+	nodes := make(map[string]struct{})
+	addNode := func(node TopologyNode) {
+		if _, ok := nodes[node.ID]; ok {
+			return
+		}
+		nodes[node.ID] = struct{}{}
+		top.Nodes = append(top.Nodes, node)
+	}
+
+	// route names reported in STATSZ are server names
+	serverIDs := make(map[string]string, len(varz))
+	clusters := make(map[string][]string)
+	for _, resp := range varz {
+		addNode(TopologyNode{
+			ID:        resp.Server.ID,
+			Kind:      NodeServer,
+			Name:      serverName(resp.Server),
+			Cluster:   resp.Server.Cluster,
+			Domain:    resp.Server.Domain,
+			Tags:      resp.Server.Tags,
+			JetStream: resp.Server.JetStream,
+		})
+		serverIDs[resp.Server.Name] = resp.Server.ID
+		if resp.Server.Cluster != "" {
+			clusters[resp.Server.Cluster] = append(clusters[resp.Server.Cluster], resp.Server.ID)
+		}
+	}
+
+	statszByID := make(map[string]*ServerStats, len(statsz))
+	for i := range statsz {
+		statszByID[statsz[i].Server.ID] = &statsz[i].Statsz
+	}
+
+	for _, resp := range varz {
+		from := resp.Server.ID
+		stats := statszByID[from]
+
+		routed := make(map[string]struct{})
+		gateways := make(map[string]struct{})
+		if stats != nil {
+			for _, route := range stats.Routes {
+				if route == nil {
+					continue
+				}
+				to, ok := serverIDs[route.Name]
+				if !ok {
+					to = route.Name
+					addNode(TopologyNode{ID: to, Kind: NodeServer, Name: route.Name, Cluster: resp.Server.Cluster})
+				}
+				routed[to] = struct{}{}
+				top.Edges = append(top.Edges, TopologyEdge{
+					Kind:     EdgeRoute,
+					From:     from,
+					To:       to,
+					Sent:     route.Sent,
+					Received: route.Received,
+					Pending:  route.Pending,
+				})
+			}
+			for _, gateway := range stats.Gateways {
+				if gateway == nil || gateway.Name == resp.Server.Cluster {
+					continue
+				}
+				gateways[gateway.Name] = struct{}{}
+				to := clusterNodeID(gateway.Name)
+				addNode(TopologyNode{ID: to, Kind: NodeCluster, Name: gateway.Name, Cluster: gateway.Name})
+				top.Edges = append(top.Edges, TopologyEdge{
+					Kind:     EdgeGateway,
+					From:     from,
+					To:       to,
+					Sent:     gateway.Sent,
+					Received: gateway.Received,
+				})
+			}
+
+			// missing connections can only be detected if STATSZ lists established ones
+			for _, peer := range clusters[resp.Server.Cluster] {
+				if _, ok := routed[peer]; !ok && peer != from {
+					top.Edges = append(top.Edges, TopologyEdge{Kind: EdgeRoute, From: from, To: peer, Missing: true})
+				}
+			}
+			for _, gateway := range resp.Varz.Gateway.Gateways {
+				if _, ok := gateways[gateway.Name]; ok || gateway.Name == resp.Server.Cluster {
+					continue
+				}
+				to := clusterNodeID(gateway.Name)
+				addNode(TopologyNode{ID: to, Kind: NodeCluster, Name: gateway.Name, Cluster: gateway.Name})
+				top.Edges = append(top.Edges, TopologyEdge{Kind: EdgeGateway, From: from, To: to, Missing: true})
+			}
+		}
+
+		for _, remote := range resp.Varz.LeafNode.Remotes {
+			if len(remote.URLs) == 0 {
+				continue
+			}
+			to, ok := resolveLeafRemote(remote.URLs, varz)
+			if !ok {
+				to = "remote:" + remote.URLs[0]
+				addNode(TopologyNode{ID: to, Kind: NodeRemote, Name: remote.URLs[0]})
+			}
+			top.Edges = append(top.Edges, TopologyEdge{
+				Kind:    EdgeLeafNode,
+				From:    from,
+				To:      to,
+				Account: remote.LocalAccount,
+			})
+		}
+	}
+
+	sort.SliceStable(top.Nodes, func(i, j int) bool {
+		if top.Nodes[i].Cluster != top.Nodes[j].Cluster {
+			return top.Nodes[i].Cluster < top.Nodes[j].Cluster
+		}
+		return top.Nodes[i].Name < top.Nodes[j].Name
+	})
+	return top
+}
+
+func clusterNodeID(name string) string {
+	return "cluster:" + name
+}
+
+// resolveLeafRemote finds the server listening for leafnode connections on one of the remote URLs.
+// Servers listening on all interfaces are matched by port only, as long as the match is unique.
+func resolveLeafRemote(urls []string, varz []VarzResp) (string, bool) {
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(parsed.Port())
+		if err != nil {
+			continue
+		}
+		var matches []string
+		for _, resp := range varz {
+			if resp.Varz.LeafNode.Port != port {
+				continue
+			}
+			host := resp.Varz.LeafNode.Host
+			if host == parsed.Hostname() || host == "" || net.ParseIP(host).IsUnspecified() {
+				matches = append(matches, resp.Server.ID)
+			}
+		}
+		if len(matches) == 1 {
+			return matches[0], true
+		}
+	}
+	return "", false
+}
+
+// MissingEdges returns edges which are expected, but not established.
+func (t *Topology) MissingEdges() []TopologyEdge {
+	missing := make([]TopologyEdge, 0)
+	for _, edge := range t.Edges {
+		if edge.Missing {
+			missing = append(missing, edge)
+		}
+	}
+	return missing
+}
+
+// WriteJSON writes the topology as JSON.
+func (t *Topology) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// WriteDOT writes the topology as a Graphviz graph.
+// Servers are grouped by cluster, edges are labeled with the number of messages sent
+// and missing edges are drawn as dotted red lines.
+func (t *Topology) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph nats {")
+
+	clusters := make(map[string][]TopologyNode)
+	var clusterNames []string
+	for _, node := range t.Nodes {
+		if node.Kind != NodeServer || node.Cluster == "" {
+			fmt.Fprintf(bw, "  %s;\n", dotNode(node))
+			continue
+		}
+		if _, ok := clusters[node.Cluster]; !ok {
+			clusterNames = append(clusterNames, node.Cluster)
+		}
+		clusters[node.Cluster] = append(clusters[node.Cluster], node)
+	}
+	for i, name := range clusterNames {
+		fmt.Fprintf(bw, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(bw, "    label=%s;\n", dotQuote(name))
+		for _, node := range clusters[name] {
+			fmt.Fprintf(bw, "    %s;\n", dotNode(node))
+		}
+		fmt.Fprintln(bw, "  }")
+	}
+
+	for _, edge := range t.Edges {
+		attrs := []string{"label=" + dotQuote(string(edge.Kind))}
+		if !edge.Missing && edge.Kind != EdgeLeafNode {
+			attrs[0] = "label=" + dotQuote(fmt.Sprintf("%s\n%d msgs", edge.Kind, edge.Sent.Msgs))
+		}
+		switch edge.Kind {
+		case EdgeGateway:
+			attrs = append(attrs, "style=bold")
+		case EdgeLeafNode:
+			attrs = append(attrs, "style=dashed")
+		}
+		if edge.Missing {
+			attrs = append(attrs, "style=dotted", "color=red")
+		}
+		fmt.Fprintf(bw, "  %s -> %s [%s];\n", dotQuote(edge.From), dotQuote(edge.To), strings.Join(attrs, ", "))
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func dotNode(node TopologyNode) string {
+	label := node.Name
+	if node.Domain != "" {
+		label += "\ndomain: " + node.Domain
+	}
+	if len(node.Tags) > 0 {
+		label += "\ntags: " + strings.Join(node.Tags, ",")
+	}
+	shape := "box"
+	switch node.Kind {
+	case NodeCluster:
+		shape = "doubleoctagon"
+	case NodeRemote:
+		shape = "ellipse"
+	}
+	return fmt.Sprintf("%s [label=%s, shape=%s]", dotQuote(node.ID), dotQuote(label), shape)
+}
+
+// dotQuote returns a quoted DOT string, with new lines converted to DOT line breaks.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return "\"" + s + "\""
+}
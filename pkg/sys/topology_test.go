@@ -0,0 +1,170 @@
+package sys
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestTopology(t *testing.T) {
+	c := SetupCluster(t)
+	defer c.Shutdown()
+
+	var urls []string
+	for _, s := range c.servers {
+		urls = append(urls, s.ClientURL())
+	}
+	sysConn, err := nats.Connect(strings.Join(urls, ","), nats.UserInfo("admin", "s3cr3t!"))
+	if err != nil {
+		t.Fatalf("Error establishing connection: %s", err)
+	}
+	defer sysConn.Close()
+
+	sys, err := NewSysClient(sysConn, ServerCount(3))
+	if err != nil {
+		t.Fatalf("Error creating system client: %s", err)
+	}
+
+	top, err := sys.Topology()
+	if err != nil {
+		t.Fatalf("Unable to fetch topology: %s", err)
+	}
+	if len(top.Nodes) != 3 {
+		t.Fatalf("Invalid number of nodes: %d; want: %d", len(top.Nodes), 3)
+	}
+	for _, node := range top.Nodes {
+		if node.Kind != NodeServer || node.Cluster != "C1" {
+			t.Fatalf("Invalid node: %+v", node)
+		}
+	}
+	// full mesh of routes, reported by both ends
+	if len(top.Edges) != 6 {
+		t.Fatalf("Invalid number of edges: %d; want: %d", len(top.Edges), 6)
+	}
+	if missing := top.MissingEdges(); len(missing) != 0 {
+		t.Fatalf("Unexpected missing edges: %+v", missing)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := top.WriteJSON(buf); err != nil {
+		t.Fatalf("Unable to write JSON: %s", err)
+	}
+	var decoded Topology
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Unable to read JSON: %s", err)
+	}
+	if len(decoded.Nodes) != 3 || len(decoded.Edges) != 6 {
+		t.Fatalf("Invalid JSON output: %+v", decoded)
+	}
+}
+
+func TestTopologyGraph(t *testing.T) {
+	varz := []VarzResp{
+		{
+			Server: ServerInfo{ID: "A1", Name: "a1", Cluster: "A", Tags: []string{"az:east"}},
+			Varz: Varz{
+				Gateway:  GatewayOptsVarz{Name: "A", Gateways: []RemoteGatewayOptsVarz{{Name: "A"}, {Name: "B"}, {Name: "C"}}},
+				LeafNode: LeafNodeOptsVarz{Host: "0.0.0.0", Port: 7422},
+			},
+		},
+		{
+			Server: ServerInfo{ID: "A2", Name: "a2", Cluster: "A"},
+			Varz:   Varz{},
+		},
+		{
+			Server: ServerInfo{ID: "L1", Name: "leaf", Domain: "edge"},
+			Varz: Varz{LeafNode: LeafNodeOptsVarz{Remotes: []RemoteLeafOptsVarz{
+				{LocalAccount: "APP", URLs: []string{"nats-leaf://10.0.0.1:7422"}},
+				{URLs: []string{"nats-leaf://example.com:7000"}},
+			}}},
+		},
+	}
+	statsz := []ServerStatszResp{
+		{
+			Server: ServerInfo{ID: "A1"},
+			Statsz: ServerStats{
+				Routes:   []*RouteStat{{Name: "a2", Sent: DataStats{Msgs: 10}}},
+				Gateways: []*GatewayStat{{Name: "B", Sent: DataStats{Msgs: 5}}},
+			},
+		},
+		{Server: ServerInfo{ID: "A2"}},
+	}
+
+	top := topology(varz, statsz)
+
+	nodes := make(map[string]TopologyNode)
+	for _, node := range top.Nodes {
+		nodes[node.ID] = node
+	}
+	expectedNodes := map[string]NodeKind{
+		"A1":                                  NodeServer,
+		"A2":                                  NodeServer,
+		"L1":                                  NodeServer,
+		"cluster:B":                           NodeCluster,
+		"cluster:C":                           NodeCluster,
+		"remote:nats-leaf://example.com:7000": NodeRemote,
+	}
+	if len(nodes) != len(expectedNodes) {
+		t.Fatalf("Invalid nodes: %+v", top.Nodes)
+	}
+	for id, kind := range expectedNodes {
+		if nodes[id].Kind != kind {
+			t.Fatalf("Invalid node %q; want kind: %s; got: %+v", id, kind, nodes[id])
+		}
+	}
+
+	type edgeKey struct {
+		kind     EdgeKind
+		from, to string
+		missing  bool
+	}
+	edges := make(map[edgeKey]TopologyEdge)
+	for _, edge := range top.Edges {
+		edges[edgeKey{edge.Kind, edge.From, edge.To, edge.Missing}] = edge
+	}
+	expectedEdges := []edgeKey{
+		{EdgeRoute, "A1", "A2", false},
+		{EdgeRoute, "A2", "A1", true},
+		{EdgeGateway, "A1", "cluster:B", false},
+		{EdgeGateway, "A1", "cluster:C", true},
+		{EdgeLeafNode, "L1", "A1", false},
+		{EdgeLeafNode, "L1", "remote:nats-leaf://example.com:7000", false},
+	}
+	if len(edges) != len(expectedEdges) {
+		t.Fatalf("Invalid edges: %+v", top.Edges)
+	}
+	for _, key := range expectedEdges {
+		if _, ok := edges[key]; !ok {
+			t.Fatalf("Missing edge %+v; got: %+v", key, top.Edges)
+		}
+	}
+	if edges[expectedEdges[0]].Sent.Msgs != 10 || edges[expectedEdges[4]].Account != "APP" {
+		t.Fatalf("Invalid edge details: %+v", top.Edges)
+	}
+	if len(top.MissingEdges()) != 2 {
+		t.Fatalf("Invalid missing edges: %+v", top.MissingEdges())
+	}
+
+	buf := &bytes.Buffer{}
+	if err := top.WriteDOT(buf); err != nil {
+		t.Fatalf("Unable to write DOT: %s", err)
+	}
+	dot := buf.String()
+	for _, expected := range []string{
+		"digraph nats {",
+		`label="A";`,
+		`"A1" [label="a1\ntags: az:east", shape=box];`,
+		`"L1" [label="leaf\ndomain: edge", shape=box];`,
+		`"A1" -> "A2" [label="route\n10 msgs"];`,
+		`"A2" -> "A1" [label="route", style=dotted, color=red];`,
+		`"A1" -> "cluster:B" [label="gateway\n5 msgs", style=bold];`,
+		`"L1" -> "A1" [label="leafnode", style=dashed];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Fatalf("Expected DOT output to contain %q; got:\n%s", expected, dot)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package sys
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/nats-io/jwt"
+	"github.com/nats-io/nats.go"
 )
 
 type (
@@ -239,14 +241,16 @@ type (
 
 // Varz returns general server information
 func (s *System) Varz(id string, opts VarzEventOptions) (*VarzResp, error) {
-	conn := s.nc
 	subj := fmt.Sprintf(srvVarzSubj, id)
 	payload, err := json.Marshal(opts)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := conn.Request(subj, payload, s.opts.timeout)
+	resp, err := s.request(subj, payload)
 	if err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidServerID, id)
+		}
 		return nil, err
 	}
 